@@ -0,0 +1,102 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"isaac/sim"
+)
+
+const gameSpeedStep = 0.1
+
+// updateAccessibility handles the in-session keys: F9 cycles the palette and
+// F7/F8 step the game speed. settings.json keeps the startup values.
+func (g *Game) updateAccessibility() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF9) {
+		i := 0
+		for j, name := range sim.PaletteNames {
+			if name == g.Settings.Palette {
+				i = j
+			}
+		}
+		g.Settings.Palette = sim.PaletteNames[(i+1)%len(sim.PaletteNames)]
+		sim.ApplyPalette(g.Settings.Palette)
+		g.StatusText = g.Tr("access.palette", g.Tr("palette."+g.Settings.Palette))
+		g.StatusTextTick = 120
+	}
+	step := 0.0
+	if inpututil.IsKeyJustPressed(ebiten.KeyF7) {
+		step = -gameSpeedStep
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF8) {
+		step = gameSpeedStep
+	}
+	if step != 0 {
+		g.Settings.GameSpeed = sim.Clamp(math.Round((g.Settings.GameSpeed+step)*10)/10, sim.MinGameSpeed, sim.MaxGameSpeed)
+		g.StatusText = g.Tr("access.speed", int(math.Round(g.Settings.GameSpeed*100)))
+		g.StatusTextTick = 120
+	}
+}
+
+// flashOn is the on phase of a blink driven by counter t. With reduced
+// flashing it stays on, so the warning becomes a steady tint.
+func (g *Game) flashOn(t, period int) bool {
+	return g.Settings.ReducedFlashing || (t/period)%2 == 0
+}
+
+// drawEnemyShape gives each enemy kind its own silhouette when shapes are
+// on, so kinds can be told apart without relying on colour.
+func (g *Game) drawEnemyShape(dst *ebiten.Image, e sim.Enemy, x, y, r float32, col color.RGBA) {
+	sides, inner := 0, float32(0)
+	if g.Settings.EnemyShapes {
+		switch e.Kind {
+		case sim.EnemyWander:
+			sides = 4
+		case sim.EnemyShooter:
+			sides = 3
+		case sim.EnemyDasher:
+			sides, inner = 5, 0.5
+		}
+	}
+	if sides == 0 {
+		vector.DrawFilledCircle(dst, x, y, r, col, false)
+		if sim.CurrentPalette().Width > 0 {
+			vector.StrokeCircle(dst, x, y, r, sim.CurrentPalette().Width, sim.CurrentPalette().Outline, false)
+		}
+		return
+	}
+	p := shapePath(x, y, r*1.15, sides, inner)
+	fillPath(dst, p, col)
+	if sim.CurrentPalette().Width > 0 {
+		strokePath(dst, p, sim.CurrentPalette().Width, sim.CurrentPalette().Outline)
+	}
+}
+
+// shapePath is a regular polygon pointing up, or a star when inner is the
+// ratio of the inner radius.
+func shapePath(x, y, r float32, sides int, inner float32) *vector.Path {
+	var p vector.Path
+	n := sides
+	if inner > 0 {
+		n *= 2
+	}
+	for i := 0; i < n; i++ {
+		a := -math.Pi/2 + 2*math.Pi*float64(i)/float64(n)
+		rr := r
+		if inner > 0 && i%2 == 1 {
+			rr *= inner
+		}
+		px, py := x+rr*float32(math.Cos(a)), y+rr*float32(math.Sin(a))
+		if i == 0 {
+			p.MoveTo(px, py)
+		} else {
+			p.LineTo(px, py)
+		}
+	}
+	p.Close()
+	return &p
+}
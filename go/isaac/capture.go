@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"isaac/capture"
+	"isaac/sim"
+)
+
+// The clip buffer keeps the last captureSeconds of play at half size,
+// grabbing a frame every captureEvery ticks.
+const (
+	captureDir     = "captures"
+	captureSeconds = 10
+	captureEvery   = 6
+	captureScale   = 0.5
+)
+
+type recorder struct {
+	clips    *capture.Ring
+	small    *ebiten.Image
+	lastTick int
+	shot     bool
+	clip     bool
+}
+
+func newRecorder() recorder {
+	return recorder{
+		clips: capture.NewRing(captureSeconds * sim.SimTPS / captureEvery),
+		small: ebiten.NewImage(int(sim.ScreenW*captureScale), int(sim.ScreenH*captureScale)),
+	}
+}
+
+// updateCapture latches the capture keys; the grabs happen in Draw, once the
+// frame is on screen.
+func (g *Game) updateCapture() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF12) {
+		g.rec.shot = true
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF11) {
+		g.rec.clip = true
+	}
+}
+
+// captureFrame runs at the end of Draw. It feeds the clip buffer and writes
+// any screenshot or clip asked for since the last frame.
+func (g *Game) captureFrame(screen *ebiten.Image) {
+	v := newHUDView(screen)
+	area := image.Rect(int(v.x), int(v.y), int(v.x+sim.ScreenW*v.scale), int(v.y+sim.ScreenH*v.scale))
+	frame := screen.SubImage(area).(*ebiten.Image)
+	if g.RunTicks < g.rec.lastTick {
+		g.rec.clips.Reset()
+		g.rec.lastTick = 0
+	}
+	if g.RunTicks-g.rec.lastTick >= captureEvery || g.rec.clips.Len() == 0 {
+		g.rec.lastTick = g.RunTicks
+		g.rec.small.Clear()
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(-float64(area.Min.X), -float64(area.Min.Y))
+		op.GeoM.Scale(captureScale/v.scale, captureScale/v.scale)
+		op.Filter = ebiten.FilterLinear
+		g.rec.small.DrawImage(frame, op)
+		g.rec.clips.Push(readImage(g.rec.small))
+	}
+	if g.rec.shot {
+		g.rec.shot = false
+		path := g.capturePath("png")
+		err := os.MkdirAll(captureDir, 0o755)
+		if err == nil {
+			err = capture.WritePNG(path, readImage(frame))
+		}
+		g.captureStatus(path, err)
+	}
+	if g.rec.clip {
+		g.rec.clip = false
+		path := g.capturePath("gif")
+		err := os.MkdirAll(captureDir, 0o755)
+		if err == nil {
+			err = capture.WriteGIF(path, g.rec.clips.Frames(), captureEvery*100/sim.SimTPS)
+		}
+		if err == nil {
+			err = g.Replay().Save(path[:len(path)-len(".gif")] + ".json")
+		}
+		g.captureStatus(path, err)
+	}
+}
+
+func readImage(src *ebiten.Image) *image.RGBA {
+	b := src.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	src.ReadPixels(img.Pix)
+	return img
+}
+
+// capturePath names a capture after the seed and the wall clock, so a bug
+// report can be replayed from the file name alone.
+func (g *Game) capturePath(ext string) string {
+	name := fmt.Sprintf("isaac_%d_%s.%s", g.RunSeed, time.Now().Format("20060102_150405"), ext)
+	return filepath.Join(captureDir, name)
+}
+
+func (g *Game) captureStatus(path string, err error) {
+	if err != nil {
+		g.StatusText = g.Tr("capture.failed", err)
+	} else {
+		g.StatusText = g.Tr("capture.saved", path)
+	}
+	g.StatusTextTick = 120
+}
@@ -0,0 +1,122 @@
+// Package capture keeps recent frames and writes them out as PNG or GIF. It
+// works on plain images, so the window and the headless renderer share it.
+package capture
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+)
+
+// Ring holds the last Cap frames, oldest first once full.
+type Ring struct {
+	frames []*image.RGBA
+	next   int
+	full   bool
+}
+
+func NewRing(n int) *Ring { return &Ring{frames: make([]*image.RGBA, n)} }
+
+func (r *Ring) Cap() int { return len(r.frames) }
+
+func (r *Ring) Len() int {
+	if r.full {
+		return len(r.frames)
+	}
+	return r.next
+}
+
+// Push stores a frame, overwriting the oldest when full. The ring keeps the
+// image, so callers hand over a fresh one each time.
+func (r *Ring) Push(img *image.RGBA) {
+	r.frames[r.next] = img
+	r.next++
+	if r.next == len(r.frames) {
+		r.next, r.full = 0, true
+	}
+}
+
+// Frames returns the stored frames, oldest first.
+func (r *Ring) Frames() []*image.RGBA {
+	if !r.full {
+		return append([]*image.RGBA(nil), r.frames[:r.next]...)
+	}
+	return append(append([]*image.RGBA(nil), r.frames[r.next:]...), r.frames[:r.next]...)
+}
+
+func (r *Ring) Reset() {
+	clear(r.frames)
+	r.next, r.full = 0, false
+}
+
+// The GIF palette is a fixed 6x7x6 colour cube, finer in green where the eye
+// is most sensitive. A fixed cube maps each pixel in constant time, which
+// matters for hundreds of frames; a per-frame palette would look better but
+// cost seconds.
+const (
+	cubeR = 6
+	cubeG = 7
+	cubeB = 6
+)
+
+var cubePalette = func() color.Palette {
+	p := make(color.Palette, 0, cubeR*cubeG*cubeB)
+	for r := 0; r < cubeR; r++ {
+		for g := 0; g < cubeG; g++ {
+			for b := 0; b < cubeB; b++ {
+				p = append(p, color.RGBA{R: uint8(r * 255 / (cubeR - 1)), G: uint8(g * 255 / (cubeG - 1)), B: uint8(b * 255 / (cubeB - 1)), A: 255})
+			}
+		}
+	}
+	return p
+}()
+
+func cubeIndex(v uint8, steps int) int { return (int(v)*(steps-1) + 127) / 255 }
+
+// Quantize maps a frame onto the GIF palette.
+func Quantize(img *image.RGBA) *image.Paletted {
+	b := img.Bounds()
+	out := image.NewPaletted(b, cubePalette)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		src := img.Pix[img.PixOffset(b.Min.X, y):]
+		dst := out.Pix[out.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			r, g, bl := src[x*4], src[x*4+1], src[x*4+2]
+			dst[x] = uint8((cubeIndex(r, cubeR)*cubeG+cubeIndex(g, cubeG))*cubeB + cubeIndex(bl, cubeB))
+		}
+	}
+	return out
+}
+
+// WriteGIF encodes the frames as a looping animation, delay hundredths of a
+// second apart.
+func WriteGIF(path string, frames []*image.RGBA, delay int) error {
+	anim := &gif.GIF{}
+	for _, f := range frames {
+		anim.Image = append(anim.Image, Quantize(f))
+		anim.Delay = append(anim.Delay, delay)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(f, anim); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func WritePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
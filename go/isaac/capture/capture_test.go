@@ -0,0 +1,28 @@
+package capture
+
+import (
+	"image"
+	"testing"
+)
+
+func TestRingWraps(t *testing.T) {
+	r := NewRing(3)
+	frames := make([]*image.RGBA, 5)
+	for i := range frames {
+		frames[i] = image.NewRGBA(image.Rect(0, 0, 1, 1))
+		r.Push(frames[i])
+		if want := min(i+1, 3); r.Len() != want {
+			t.Fatalf("after %d pushes Len is %d, want %d", i+1, r.Len(), want)
+		}
+	}
+	got := r.Frames()
+	for i, f := range got {
+		if f != frames[i+2] {
+			t.Errorf("frame %d is not push %d", i, i+2)
+		}
+	}
+	r.Reset()
+	if r.Len() != 0 || len(r.Frames()) != 0 {
+		t.Error("Reset left frames behind")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"isaac/sim"
+)
+
+func (g *Game) openCharacterSelect() {
+	g.Scene = sim.SceneCharacterSelect
+	g.selectIndex = 0
+	for i, c := range sim.CharacterRoster {
+		if c.ID == g.CharacterID {
+			g.selectIndex = i
+		}
+	}
+}
+
+func (g *Game) updateCharacterSelect() {
+	n := len(sim.CharacterRoster)
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) || inpututil.IsKeyJustPressed(ebiten.KeyA) {
+		g.selectIndex = (g.selectIndex + n - 1) % n
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) || inpututil.IsKeyJustPressed(ebiten.KeyD) {
+		g.selectIndex = (g.selectIndex + 1) % n
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyB) {
+		g.openLeaderboard()
+		return
+	}
+	modes := len(sim.RunModes)
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) || inpututil.IsKeyJustPressed(ebiten.KeyW) {
+		g.Mode = sim.RunModes[(int(g.Mode)+modes-1)%modes]
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) || inpututil.IsKeyJustPressed(ebiten.KeyS) {
+		g.Mode = sim.RunModes[(int(g.Mode)+1)%modes]
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) || inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+		g.CharacterID = sim.CharacterRoster[g.selectIndex].ID
+		g.Scene = sim.ScenePlaying
+		g.StartNewRun()
+		g.SaveMeta()
+	}
+}
+
+func (g *Game) drawCharacterSelect(screen *ebiten.Image) {
+	screen.Fill(color.RGBA{R: 32, G: 26, B: 24, A: 255})
+	ebitenutil.DebugPrintAt(screen, g.Tr("select.help"), sim.ScreenW/2-250, 60)
+	ebitenutil.DebugPrintAt(screen, g.Tr("select.mode", g.ModeLabel(g.Mode), g.ModeBest[g.Mode.String()]), sim.ScreenW/2-90, 90)
+	if g.Mode == sim.ModeEndless && len(g.EndlessFloors) > 0 {
+		ebitenutil.DebugPrintAt(screen, g.Tr("select.deepest", g.EndlessFloors), sim.ScreenW/2-90, 110)
+	}
+	spacing := float32(sim.ScreenW-160) / float32(len(sim.CharacterRoster))
+	for i, c := range sim.CharacterRoster {
+		x := 80 + spacing*float32(i) + spacing/2
+		y := float32(200)
+		if i == g.selectIndex {
+			vector.StrokeCircle(screen, x, y, 34, 3, color.RGBA{R: 235, G: 215, B: 120, A: 255}, false)
+		}
+		vector.DrawFilledCircle(screen, x, y, sim.PlayerRadius*1.6, c.Color, false)
+		ebitenutil.DebugPrintAt(screen, c.Name, int(x)-len(c.Name)*3, int(y)+44)
+	}
+	c := sim.CharacterRoster[g.selectIndex]
+	lines := []string{
+		c.Name + ": " + g.Tr("character."+c.ID),
+		g.Tr("select.stats", c.MaxHP, c.Speed, c.Damage, c.DamageMult, c.FireCooldown, int(c.Crit*100)),
+		g.Tr("select.loadout", c.Bombs, c.Coins, c.Keys, c.Shield, g.Tr(sim.ActiveDefs[c.Active].Key)),
+	}
+	for i, line := range lines {
+		ebitenutil.DebugPrintAt(screen, line, 120, 330+i*20)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+
+	"isaac/sim"
+)
+
+const consoleLogLines = 8
+
+// updateConsole handles the ` console. While it is open it takes all the
+// keyboard input and the simulation stands still; it reports whether it did.
+func (g *Game) updateConsole() bool {
+	if g.Scene != sim.ScenePlaying {
+		return false
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackquote) {
+		g.consoleOpen = !g.consoleOpen
+		g.consoleLine = ""
+		return true
+	}
+	if !g.consoleOpen {
+		return false
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		g.consoleOpen = false
+		return true
+	}
+	for _, r := range ebiten.AppendInputChars(nil) {
+		if r != '`' {
+			g.consoleLine += string(r)
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(g.consoleLine) > 0 {
+		_, size := utf8.DecodeLastRuneInString(g.consoleLine)
+		g.consoleLine = g.consoleLine[:len(g.consoleLine)-size]
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
+		line := strings.TrimSpace(g.consoleLine)
+		g.consoleLine = ""
+		if line != "" {
+			g.consolePrint("> " + line)
+			g.consolePrint(g.RunCommand(strings.Fields(strings.ToLower(line))))
+		}
+	}
+	return true
+}
+
+func (g *Game) consolePrint(s string) {
+	g.consoleLog = append(g.consoleLog, s)
+	if len(g.consoleLog) > consoleLogLines {
+		g.consoleLog = g.consoleLog[len(g.consoleLog)-consoleLogLines:]
+	}
+}
+
+func (g *Game) drawConsole(v hudView) {
+	h := float64(consoleLogLines+2) * 14
+	v.rect(0, 0, sim.ScreenW, h+8, hudPanelColor)
+	v.text(g.Tr("console.title"), hudMargin, 6, hudDimColor, text.AlignStart)
+	for i, l := range g.consoleLog {
+		v.text(l, hudMargin, 20+float64(i)*14, hudTextColor, text.AlignStart)
+	}
+	v.text("> "+g.consoleLine+"_", hudMargin, h-6, goldColor, text.AlignStart)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"isaac/sim"
+)
+
+const darknessAlpha = 235
+
+var curseColor = color.RGBA{R: 170, G: 120, B: 200, A: 255}
+
+var trinketColors = map[sim.TrinketType]color.RGBA{
+	sim.TrinketSwallowedPenny: {R: 200, G: 165, B: 90, A: 255},
+	sim.TrinketRageStone:      {R: 190, G: 70, B: 60, A: 255},
+	sim.TrinketSteadyHand:     {R: 150, G: 185, B: 215, A: 255},
+	sim.TrinketLuckyToe:       {R: 225, G: 190, B: 170, A: 255},
+	sim.TrinketBloodPenny:     {R: 170, G: 40, B: 55, A: 255},
+}
+
+// drawDarkness covers the room in black except for a soft circle around the
+// player. The hole is cut with destination-out rings, outermost first.
+func (g *Game) drawDarkness(dst *ebiten.Image, center sim.Vec2) {
+	if g.darkness == nil {
+		g.darkness = ebiten.NewImage(sim.ScreenW, sim.ScreenH)
+	}
+	g.darkness.Fill(color.RGBA{A: darknessAlpha})
+	for i, f := range []float64{1, 0.85, 0.7, 0.55} {
+		var p vector.Path
+		p.Arc(float32(center.X), float32(center.Y), float32(sim.DarknessRadius*f), 0, 2*math.Pi, vector.Clockwise)
+		vs, is := p.AppendVerticesAndIndicesForFilling(nil, nil)
+		for j := range vs {
+			vs[j].SrcX, vs[j].SrcY = 1, 1
+			vs[j].ColorR, vs[j].ColorG, vs[j].ColorB = 1, 1, 1
+			vs[j].ColorA = 0.25 + 0.25*float32(i)
+		}
+		g.darkness.DrawTriangles(vs, is, whitePixel, &ebiten.DrawTrianglesOptions{AntiAlias: true, Blend: ebiten.BlendDestinationOut})
+	}
+	dst.DrawImage(g.darkness, nil)
+}
+
+// drawHiddenItem is an item pedestal under the blind curse.
+func drawHiddenItem(screen *ebiten.Image, pos sim.Vec2, size float32) {
+	vector.DrawFilledRect(screen, float32(pos.X)-size/2, float32(pos.Y)-size/2, size, size, color.RGBA{R: 120, G: 115, B: 110, A: 255}, false)
+	vector.StrokeRect(screen, float32(pos.X)-size/2, float32(pos.Y)-size/2, size, size, 2, color.RGBA{R: 40, G: 30, B: 25, A: 255}, false)
+	ebitenutil.DebugPrintAt(screen, "?", int(pos.X)-3, int(pos.Y)-8)
+}
+
+func drawTrinketShape(dst *ebiten.Image, x, y, r float32, col, outline color.RGBA, width float32) {
+	var p vector.Path
+	p.MoveTo(x, y-r)
+	p.LineTo(x+r*0.7, y)
+	p.LineTo(x, y+r)
+	p.LineTo(x-r*0.7, y)
+	p.Close()
+	fillPath(dst, &p, col)
+	strokePath(dst, &p, width, outline)
+}
+
+// drawTrinketSlot shows the held trinket and the floor curse under the
+// active item.
+func (g *Game) drawTrinketSlot(v hudView, x, y float64) {
+	if g.Trinket != sim.TrinketNone {
+		px, py := v.px(x+6, y+7)
+		drawTrinketShape(v.dst, px, py, float32(7*v.scale), trinketColors[g.Trinket], color.RGBA{R: 30, G: 25, B: 20, A: 255}, float32(v.scale))
+		v.text(g.TrinketName(g.Trinket), x+18, y, hudTextColor, text.AlignStart)
+		y += 18
+	}
+	if g.Curse != sim.CurseNone {
+		v.text(g.CurseName(g.Curse), x, y, curseColor, text.AlignStart)
+	}
+}
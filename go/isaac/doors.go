@@ -0,0 +1,53 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"isaac/sim"
+)
+
+func (g *Game) drawDoors(screen *ebiten.Image) {
+	for _, d := range sim.LayoutDirs {
+		door := g.DoorToward(d[0], d[1])
+		if door == nil {
+			continue
+		}
+		state := g.EffectiveDoorState(door)
+		if state == sim.DoorSecret {
+			continue
+		}
+		c := sim.DoorCenter(d[0], d[1])
+		w, h := float32(sim.DoorHalf*2), float32(6)
+		if d[0] != 0 {
+			w, h = h, w
+		}
+		x, y := float32(c.X)-w/2, float32(c.Y)-h/2
+		vector.DrawFilledRect(screen, x, y, w, h, g.doorColorFor(door), false)
+		if state == sim.DoorLocked {
+			vector.DrawFilledCircle(screen, float32(c.X), float32(c.Y), 4, color.RGBA{R: 40, G: 30, B: 20, A: 255}, false)
+		}
+		if door.Boss {
+			vector.StrokeRect(screen, x-2, y-2, w+4, h+4, 2, color.RGBA{R: 200, G: 60, B: 55, A: 255}, false)
+		}
+	}
+}
+
+func (g *Game) doorColorFor(d *sim.Door) color.RGBA {
+	if col, ok := sim.CurrentPalette().Doors[g.EffectiveDoorState(d)]; ok {
+		return col
+	}
+	return sim.CurrentPalette().Doors[sim.DoorOpen]
+}
+
+func doorMapColor(state sim.DoorState) color.RGBA {
+	switch state {
+	case sim.DoorLocked:
+		return color.RGBA{R: 215, G: 180, B: 85, A: 255}
+	case sim.DoorBoss:
+		return color.RGBA{R: 170, G: 50, B: 45, A: 255}
+	}
+	return color.RGBA{R: 150, G: 140, B: 125, A: 255}
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"isaac/sim"
+)
+
+type EditorTool int
+
+const (
+	ToolHazard EditorTool = iota
+	ToolChest
+	ToolEnemySlot
+)
+
+const (
+	editorGrid      = 20
+	editorHandle    = 12
+	doorClearance   = 24
+	wallClearance   = 8
+	hazardMinR      = 8
+	hazardMaxR      = 40
+	hazardDefaultR  = 16
+	chestHalfExtent = 14
+)
+
+var editorToolKeys = map[EditorTool]string{
+	ToolHazard:    "editor.tool_hazard",
+	ToolChest:     "editor.tool_chest",
+	ToolEnemySlot: "editor.tool_slot",
+}
+
+// Editor is the state of the room template editor. It works on its own copy
+// of the templates; the game only sees them once they are saved.
+type Editor struct {
+	Templates []sim.RoomTemplate
+	Index     int
+	Tool      EditorTool
+	Snap      bool
+	Dirty     bool
+
+	dragging  bool
+	dragTool  EditorTool
+	dragIndex int
+}
+
+func cloneTemplates(list []sim.RoomTemplate) []sim.RoomTemplate {
+	out := make([]sim.RoomTemplate, len(list))
+	for i, t := range list {
+		out[i] = sim.RoomTemplate{
+			Name:       t.Name,
+			Hazards:    append([]sim.Hazard(nil), t.Hazards...),
+			ChestPos:   append([]sim.Vec2(nil), t.ChestPos...),
+			EnemySlots: append([]sim.Vec2(nil), t.EnemySlots...),
+		}
+	}
+	return out
+}
+
+func (g *Game) openEditor() {
+	g.editorMode = true
+	g.editor = Editor{Templates: cloneTemplates(g.RoomTemplates()), Snap: true}
+	g.Scene = sim.SceneEditor
+}
+
+// returnToEditor leaves a playtest. The run keeps going in the background
+// until the next playtest replaces it.
+func (g *Game) returnToEditor() {
+	g.Templates = sim.LoadRoomTemplates()
+	g.Scene = sim.SceneEditor
+	g.StatusText = ""
+	g.StatusTextTick = 0
+}
+
+func (e *Editor) current() *sim.RoomTemplate { return &e.Templates[e.Index] }
+
+func (e *Editor) snap(p sim.Vec2) sim.Vec2 {
+	if !e.Snap {
+		return p
+	}
+	return sim.Vec2{X: math.Round(p.X/editorGrid) * editorGrid, Y: math.Round(p.Y/editorGrid) * editorGrid}
+}
+
+func toolExtent(tool EditorTool, r float64) float64 {
+	switch tool {
+	case ToolHazard:
+		return r
+	case ToolChest:
+		return chestHalfExtent
+	}
+	return editorHandle
+}
+
+// placementError reports why something of the given extent cannot sit at p:
+// it has to stay inside the walls and clear of every door, so the player can
+// always walk in and out of the room. It returns "" when p is fine.
+func (g *Game) placementError(p sim.Vec2, extent float64) string {
+	lo := sim.RoomMargin + wallClearance + extent
+	if p.X < lo || p.Y < lo || p.X > sim.ScreenW-lo || p.Y > sim.ScreenH-lo {
+		return g.Tr("editor.in_wall")
+	}
+	for _, d := range sim.LayoutDirs {
+		if sim.Distance(p, sim.DoorCenter(d[0], d[1])) < sim.DoorHalf+doorClearance+extent {
+			return g.Tr("editor.in_door")
+		}
+	}
+	return ""
+}
+
+// templateError checks a whole template before it is saved or playtested.
+func (g *Game) templateError(t sim.RoomTemplate) string {
+	if len(t.EnemySlots) == 0 {
+		return g.Tr("editor.need_slot", t.Name)
+	}
+	for _, h := range t.Hazards {
+		if msg := g.placementError(h.Pos, h.R); msg != "" {
+			return t.Name + ": " + msg
+		}
+	}
+	for _, c := range t.ChestPos {
+		if msg := g.placementError(c, chestHalfExtent); msg != "" {
+			return t.Name + ": " + msg
+		}
+	}
+	for _, s := range t.EnemySlots {
+		if msg := g.placementError(s, editorHandle); msg != "" {
+			return t.Name + ": " + msg
+		}
+	}
+	return ""
+}
+
+// handleAt finds the element under p, checking slots and chests before the
+// larger hazards underneath them.
+func (e *Editor) handleAt(p sim.Vec2) (EditorTool, int, bool) {
+	t := e.current()
+	for i := len(t.EnemySlots) - 1; i >= 0; i-- {
+		if sim.Distance(p, t.EnemySlots[i]) <= editorHandle {
+			return ToolEnemySlot, i, true
+		}
+	}
+	for i := len(t.ChestPos) - 1; i >= 0; i-- {
+		if math.Abs(p.X-t.ChestPos[i].X) <= chestHalfExtent && math.Abs(p.Y-t.ChestPos[i].Y) <= chestHalfExtent {
+			return ToolChest, i, true
+		}
+	}
+	for i := len(t.Hazards) - 1; i >= 0; i-- {
+		if sim.Distance(p, t.Hazards[i].Pos) <= math.Max(t.Hazards[i].R, editorHandle) {
+			return ToolHazard, i, true
+		}
+	}
+	return 0, 0, false
+}
+
+func (e *Editor) position(tool EditorTool, i int) (sim.Vec2, float64) {
+	t := e.current()
+	switch tool {
+	case ToolHazard:
+		return t.Hazards[i].Pos, t.Hazards[i].R
+	case ToolChest:
+		return t.ChestPos[i], 0
+	}
+	return t.EnemySlots[i], 0
+}
+
+func (e *Editor) move(tool EditorTool, i int, p sim.Vec2) {
+	t := e.current()
+	switch tool {
+	case ToolHazard:
+		t.Hazards[i].Pos = p
+	case ToolChest:
+		t.ChestPos[i] = p
+	default:
+		t.EnemySlots[i] = p
+	}
+	e.Dirty = true
+}
+
+func (e *Editor) remove(tool EditorTool, i int) {
+	t := e.current()
+	switch tool {
+	case ToolHazard:
+		t.Hazards = append(t.Hazards[:i], t.Hazards[i+1:]...)
+	case ToolChest:
+		t.ChestPos = append(t.ChestPos[:i], t.ChestPos[i+1:]...)
+	default:
+		t.EnemySlots = append(t.EnemySlots[:i], t.EnemySlots[i+1:]...)
+	}
+	e.Dirty = true
+}
+
+func (g *Game) editorStatus(s string) {
+	g.StatusText = s
+	g.StatusTextTick = 120
+}
+
+func (g *Game) updateEditor() {
+	e := &g.editor
+	if g.StatusTextTick > 0 {
+		g.StatusTextTick--
+	}
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.Key1):
+		e.Tool = ToolHazard
+	case inpututil.IsKeyJustPressed(ebiten.Key2):
+		e.Tool = ToolChest
+	case inpututil.IsKeyJustPressed(ebiten.Key3):
+		e.Tool = ToolEnemySlot
+	case inpututil.IsKeyJustPressed(ebiten.KeyG):
+		e.Snap = !e.Snap
+	case inpututil.IsKeyJustPressed(ebiten.KeyBracketLeft):
+		e.Index = (e.Index + len(e.Templates) - 1) % len(e.Templates)
+		e.dragging = false
+	case inpututil.IsKeyJustPressed(ebiten.KeyBracketRight):
+		e.Index = (e.Index + 1) % len(e.Templates)
+		e.dragging = false
+	case inpututil.IsKeyJustPressed(ebiten.KeyN):
+		e.Templates = append(e.Templates, sim.RoomTemplate{Name: fmt.Sprintf("Custom %d", len(e.Templates)+1)})
+		e.Index = len(e.Templates) - 1
+		e.Dirty = true
+	case inpututil.IsKeyJustPressed(ebiten.KeyS):
+		g.saveEditorTemplates()
+		return
+	case inpututil.IsKeyJustPressed(ebiten.KeyF5):
+		g.playtestTemplate()
+		return
+	}
+
+	m := g.cursorPos()
+	p := e.snap(m)
+	if e.dragging {
+		if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+			e.dragging = false
+		} else if _, r := e.position(e.dragTool, e.dragIndex); g.placementError(p, toolExtent(e.dragTool, r)) == "" {
+			e.move(e.dragTool, e.dragIndex, p)
+		}
+		return
+	}
+	tool, i, hit := e.handleAt(m)
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		if hit {
+			e.dragging, e.dragTool, e.dragIndex = true, tool, i
+			return
+		}
+		if msg := g.placementError(p, toolExtent(e.Tool, hazardDefaultR)); msg != "" {
+			g.editorStatus(msg)
+			return
+		}
+		t := e.current()
+		switch e.Tool {
+		case ToolHazard:
+			t.Hazards = append(t.Hazards, sim.Hazard{Pos: p, R: hazardDefaultR})
+		case ToolChest:
+			t.ChestPos = append(t.ChestPos, p)
+		default:
+			t.EnemySlots = append(t.EnemySlots, p)
+		}
+		e.Dirty = true
+		return
+	}
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) && hit {
+		e.remove(tool, i)
+		return
+	}
+	if !hit || tool != ToolHazard {
+		return
+	}
+	grow := 0.0
+	if _, wy := ebiten.Wheel(); wy != 0 {
+		grow = math.Copysign(2, wy)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEqual) {
+		grow = 2
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyMinus) {
+		grow = -2
+	}
+	h := &e.current().Hazards[i]
+	if r := math.Max(hazardMinR, math.Min(hazardMaxR, h.R+grow)); grow != 0 && g.placementError(h.Pos, r) == "" {
+		h.R = r
+		e.Dirty = true
+	}
+}
+
+func (g *Game) saveEditorTemplates() {
+	e := &g.editor
+	for _, t := range e.Templates {
+		if msg := g.templateError(t); msg != "" {
+			g.editorStatus(msg)
+			return
+		}
+	}
+	data, err := json.MarshalIndent(e.Templates, "", "  ")
+	if err == nil {
+		err = os.WriteFile(sim.RoomTemplatesPath(), data, 0644)
+	}
+	if err != nil {
+		g.editorStatus(g.Tr("editor.save_failed", err))
+		return
+	}
+	e.Dirty = false
+	g.Templates = cloneTemplates(e.Templates)
+	g.editorStatus(g.Trn("editor.saved", len(e.Templates), len(e.Templates), sim.RoomTemplatesPath()))
+}
+
+// playtestTemplate starts a throwaway run in which the start room and every
+// combat room use the template being edited. It is flagged as cheated so it
+// never reaches the best score or the leaderboard.
+func (g *Game) playtestTemplate() {
+	tpl := *g.editor.current()
+	if msg := g.templateError(tpl); msg != "" {
+		g.editorStatus(msg)
+		return
+	}
+	g.Templates = cloneTemplates([]sim.RoomTemplate{tpl})
+	g.StartRunWithSeed(time.Now().UnixNano())
+	g.Cheated = true
+	r := g.CurrentRoom()
+	r.Hazards, r.Chests, r.Enemies = nil, nil, nil
+	g.PopulateCombatRoom(r, 1)
+	r.Reward = sim.Item{Taken: true}
+	g.LoadCurrentRoom()
+	g.Scene = sim.ScenePlaying
+	g.StatusText = g.Tr("editor.playtest", tpl.Name)
+	g.StatusTextTick = 240
+}
+
+func (g *Game) drawEditor(dst *ebiten.Image) {
+	e := &g.editor
+	t := e.current()
+	dst.Fill(color.RGBA{R: 32, G: 26, B: 24, A: 255})
+	vector.DrawFilledRect(dst, float32(sim.RoomMargin), float32(sim.RoomMargin), float32(sim.ScreenW-2*sim.RoomMargin), float32(sim.ScreenH-2*sim.RoomMargin), color.RGBA{R: 64, G: 50, B: 45, A: 255}, false)
+	vector.StrokeRect(dst, float32(sim.RoomMargin), float32(sim.RoomMargin), float32(sim.ScreenW-2*sim.RoomMargin), float32(sim.ScreenH-2*sim.RoomMargin), 6, color.RGBA{R: 100, G: 76, B: 68, A: 255}, false)
+	if e.Snap {
+		gridCol := color.RGBA{R: 80, G: 64, B: 58, A: 255}
+		for x := float32(editorGrid * 3); x < sim.ScreenW-sim.RoomMargin; x += editorGrid {
+			vector.StrokeLine(dst, x, sim.RoomMargin, x, sim.ScreenH-sim.RoomMargin, 1, gridCol, false)
+		}
+		for y := float32(editorGrid * 3); y < sim.ScreenH-sim.RoomMargin; y += editorGrid {
+			vector.StrokeLine(dst, sim.RoomMargin, y, sim.ScreenW-sim.RoomMargin, y, 1, gridCol, false)
+		}
+	}
+	for _, d := range sim.LayoutDirs {
+		c := sim.DoorCenter(d[0], d[1])
+		vector.DrawFilledCircle(dst, float32(c.X), float32(c.Y), sim.DoorHalf+doorClearance, color.RGBA{R: 120, G: 40, B: 40, A: 90}, false)
+	}
+	for _, h := range t.Hazards {
+		drawHazard(dst, h)
+	}
+	for _, c := range t.ChestPos {
+		drawChest(dst, sim.Chest{Pos: c})
+	}
+	for i, s := range t.EnemySlots {
+		vector.StrokeCircle(dst, float32(s.X), float32(s.Y), editorHandle, 2, color.RGBA{R: 210, G: 90, B: 90, A: 255}, false)
+		ebitenutil.DebugPrintAt(dst, fmt.Sprint(i+1), int(s.X)-3, int(s.Y)-8)
+	}
+
+	m := g.cursorPos()
+	if tool, i, hit := e.handleAt(m); hit || e.dragging {
+		if e.dragging {
+			tool, i = e.dragTool, e.dragIndex
+		}
+		p, r := e.position(tool, i)
+		vector.StrokeCircle(dst, float32(p.X), float32(p.Y), float32(toolExtent(tool, r)+4), 2, goldColor, false)
+	} else {
+		p := e.snap(m)
+		ghost := color.RGBA{R: 200, G: 200, B: 200, A: 160}
+		if g.placementError(p, toolExtent(e.Tool, hazardDefaultR)) != "" {
+			ghost = color.RGBA{R: 230, G: 70, B: 70, A: 160}
+		}
+		vector.StrokeCircle(dst, float32(p.X), float32(p.Y), float32(toolExtent(e.Tool, hazardDefaultR)), 1.5, ghost, false)
+	}
+
+	dirty := ""
+	if e.Dirty {
+		dirty = " *"
+	}
+	snap := g.Tr("editor.off")
+	if e.Snap {
+		snap = g.Tr("editor.on")
+	}
+	ebitenutil.DebugPrintAt(dst, g.Tr("editor.title", t.Name, e.Index+1, len(e.Templates), dirty), 60, 8)
+	ebitenutil.DebugPrintAt(dst, g.Tr("editor.tool", g.Tr(editorToolKeys[e.Tool]), snap, len(t.Hazards), len(t.ChestPos), len(t.EnemySlots)), 60, 24)
+	ebitenutil.DebugPrintAt(dst, g.Tr("editor.help"), 60, sim.ScreenH-40)
+	if g.StatusTextTick > 0 {
+		ebitenutil.DebugPrintAt(dst, g.StatusText, 60, sim.ScreenH-24)
+	}
+}
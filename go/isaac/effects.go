@@ -0,0 +1,39 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"isaac/sim"
+)
+
+func drawParticles(dst *ebiten.Image, p *sim.ParticlePool) {
+	for _, pt := range p.Particles() {
+		if !pt.Active {
+			continue
+		}
+		ratio := float32(pt.Life) / float32(pt.MaxLife)
+		col := pt.Col
+		col.A = uint8(float32(col.A) * ratio)
+		vector.DrawFilledCircle(dst, float32(pt.Pos.X), float32(pt.Pos.Y), pt.Size*(0.5+ratio*0.5), col, false)
+	}
+}
+
+// cameraOffset turns the shake timer into a screen offset. It is derived from
+// the tick counter rather than an RNG so drawing stays side-effect free.
+func (g *Game) cameraOffset() (float64, float64) {
+	if g.ShakeTick <= 0 || g.ShakeMag <= 0 {
+		return 0, 0
+	}
+	t := float64(g.RunTicks)
+	fade := math.Min(1, float64(g.ShakeTick)/8)
+	return math.Sin(t*2.3) * g.ShakeMag * fade, math.Cos(t*3.1) * g.ShakeMag * fade
+}
+
+func drawDecals(dst *ebiten.Image, decals []sim.Decal) {
+	for _, d := range decals {
+		vector.DrawFilledCircle(dst, float32(d.Pos.X), float32(d.Pos.Y), d.R, d.Col, false)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"isaac/sim"
+)
+
+var familiarColors = map[sim.FamiliarKind]color.RGBA{
+	sim.FamiliarBuddy:  {R: 225, G: 205, B: 180, A: 255},
+	sim.FamiliarSentry: {R: 170, G: 190, B: 150, A: 255},
+	sim.FamiliarHalo:   {R: 245, G: 230, B: 150, A: 255},
+	sim.FamiliarMagnet: {R: 170, G: 170, B: 200, A: 255},
+}
+
+func (g *Game) drawFamiliars(dst *ebiten.Image) {
+	for _, f := range g.Familiars {
+		p := g.lerpPos(f.Prev, f.Pos)
+		x, y := float32(p.X), float32(p.Y)
+		col := familiarColors[f.Kind]
+		switch f.Kind {
+		case sim.FamiliarHalo:
+			vector.StrokeCircle(dst, x, y, sim.FamiliarRadius, 3, col, false)
+		case sim.FamiliarMagnet:
+			vector.DrawFilledRect(dst, x-sim.FamiliarRadius, y-sim.FamiliarRadius, 2*sim.FamiliarRadius, 2*sim.FamiliarRadius, col, false)
+		default:
+			vector.DrawFilledCircle(dst, x, y, sim.FamiliarRadius, col, false)
+			vector.DrawFilledCircle(dst, x, y-1, 2, color.RGBA{R: 30, G: 20, B: 20, A: 255}, false)
+		}
+	}
+}
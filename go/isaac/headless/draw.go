@@ -0,0 +1,240 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+
+	"isaac/sim"
+)
+
+// canvas rasterises world-space shapes onto an RGBA image at a fixed scale.
+// It trades the window's antialiasing for needing no GPU.
+type canvas struct {
+	img   *image.RGBA
+	scale float64
+}
+
+func newCanvas(scale float64) canvas {
+	w, h := int(sim.ScreenW*scale), int(sim.ScreenH*scale)
+	return canvas{img: image.NewRGBA(image.Rect(0, 0, w, h)), scale: scale}
+}
+
+func (c canvas) plot(x, y int, col color.RGBA) {
+	if !(image.Point{X: x, Y: y}).In(c.img.Rect) {
+		return
+	}
+	i := c.img.PixOffset(x, y)
+	p := c.img.Pix[i : i+4 : i+4]
+	a := uint32(col.A)
+	p[0] = uint8((uint32(col.R)*a + uint32(p[0])*(255-a)) / 255)
+	p[1] = uint8((uint32(col.G)*a + uint32(p[1])*(255-a)) / 255)
+	p[2] = uint8((uint32(col.B)*a + uint32(p[2])*(255-a)) / 255)
+	p[3] = 255
+}
+
+// shade plots every pixel in the world-space box whose centre passes inside.
+func (c canvas) shade(x0, y0, x1, y1 float64, inside func(x, y float64) bool, col color.RGBA) {
+	for py := int(math.Floor(y0 * c.scale)); py <= int(math.Ceil(y1*c.scale)); py++ {
+		for px := int(math.Floor(x0 * c.scale)); px <= int(math.Ceil(x1*c.scale)); px++ {
+			if inside((float64(px)+0.5)/c.scale, (float64(py)+0.5)/c.scale) {
+				c.plot(px, py, col)
+			}
+		}
+	}
+}
+
+func (c canvas) rect(x, y, w, h float64, col color.RGBA) {
+	c.shade(x, y, x+w, y+h, func(px, py float64) bool {
+		return px >= x && px < x+w && py >= y && py < y+h
+	}, col)
+}
+
+func (c canvas) strokeRect(x, y, w, h, width float64, col color.RGBA) {
+	c.rect(x, y, w, width, col)
+	c.rect(x, y+h-width, w, width, col)
+	c.rect(x, y, width, h, col)
+	c.rect(x+w-width, y, width, h, col)
+}
+
+func (c canvas) circle(p sim.Vec2, r float64, col color.RGBA) {
+	c.shade(p.X-r, p.Y-r, p.X+r, p.Y+r, func(x, y float64) bool {
+		return math.Hypot(x-p.X, y-p.Y) <= r
+	}, col)
+}
+
+func (c canvas) ring(p sim.Vec2, r, width float64, col color.RGBA) {
+	c.shade(p.X-r-width, p.Y-r-width, p.X+r+width, p.Y+r+width, func(x, y float64) bool {
+		return math.Abs(math.Hypot(x-p.X, y-p.Y)-r) <= width/2
+	}, col)
+}
+
+func (c canvas) line(a, b sim.Vec2, width float64, col color.RGBA) {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	l2 := dx*dx + dy*dy
+	c.shade(math.Min(a.X, b.X)-width, math.Min(a.Y, b.Y)-width, math.Max(a.X, b.X)+width, math.Max(a.Y, b.Y)+width, func(x, y float64) bool {
+		t := 0.0
+		if l2 > 0 {
+			t = sim.Clamp(((x-a.X)*dx+(y-a.Y)*dy)/l2, 0, 1)
+		}
+		return math.Hypot(x-a.X-t*dx, y-a.Y-t*dy) <= width/2
+	}, col)
+}
+
+// text draws at pixel coordinates, unscaled, so the HUD stays readable in
+// small frames.
+func (c canvas) text(s string, x, y int, col color.RGBA) {
+	d := font.Drawer{Dst: c.img, Src: image.NewUniform(col), Face: basicfont.Face7x13, Dot: fixed.P(x, y)}
+	d.DrawString(s)
+}
+
+var roomTints = map[sim.RoomType]color.RGBA{
+	sim.RoomShop:     {R: 70, G: 58, B: 47, A: 255},
+	sim.RoomBoss:     {R: 72, G: 42, B: 40, A: 255},
+	sim.RoomTreasure: {R: 74, G: 66, B: 40, A: 255},
+}
+
+// drawFrame renders the room the way the window does, minus particles,
+// decals and per-kind shapes, with a one-line HUD on top.
+func drawFrame(g *sim.Game, scale float64) *image.RGBA {
+	c := newCanvas(scale)
+	pal := sim.CurrentPalette()
+	room := g.CurrentRoom()
+	c.rect(0, 0, sim.ScreenW, sim.ScreenH, color.RGBA{R: 32, G: 26, B: 24, A: 255})
+	tint, ok := roomTints[room.Type]
+	if !ok {
+		tint = color.RGBA{R: 64, G: 50, B: 45, A: 255}
+	}
+	inner := float64(sim.ScreenW - 2*sim.RoomMargin)
+	innerH := float64(sim.ScreenH - 2*sim.RoomMargin)
+	c.rect(sim.RoomMargin, sim.RoomMargin, inner, innerH, tint)
+	c.strokeRect(sim.RoomMargin-3, sim.RoomMargin-3, inner+6, innerH+6, 6, color.RGBA{R: 100, G: 76, B: 68, A: 255})
+	for _, d := range sim.LayoutDirs {
+		door := g.DoorToward(d[0], d[1])
+		if door == nil || g.EffectiveDoorState(door) == sim.DoorSecret {
+			continue
+		}
+		col, ok := pal.Doors[g.EffectiveDoorState(door)]
+		if !ok {
+			col = pal.Doors[sim.DoorOpen]
+		}
+		p := sim.DoorCenter(d[0], d[1])
+		w, h := float64(sim.DoorHalf*2), 6.0
+		if d[0] != 0 {
+			w, h = h, w
+		}
+		c.rect(p.X-w/2, p.Y-h/2, w, h, col)
+	}
+	for _, h := range g.Hazards {
+		c.circle(h.Pos, h.R, color.RGBA{R: 100, G: 48, B: 48, A: 220})
+	}
+	if g.RoomClear && !room.Reward.Taken {
+		col := color.RGBA{R: 230, G: 215, B: 120, A: 255}
+		if g.Curse == sim.CurseBlind {
+			col = color.RGBA{R: 120, G: 115, B: 110, A: 255}
+		}
+		c.rect(room.Reward.Pos.X-sim.ItemRadius, room.Reward.Pos.Y-sim.ItemRadius, 2*sim.ItemRadius, 2*sim.ItemRadius, col)
+	}
+	for _, ch := range g.Chests {
+		col := color.RGBA{R: 150, G: 105, B: 65, A: 255}
+		if ch.Opened {
+			col = color.RGBA{R: 95, G: 78, B: 62, A: 255}
+		}
+		c.rect(ch.Pos.X-14, ch.Pos.Y-10, 28, 20, col)
+	}
+	if room.Keeper {
+		c.circle(sim.Vec2{X: sim.ShopkeeperPos.X, Y: sim.ShopkeeperPos.Y + 6}, sim.ShopkeeperRadius, color.RGBA{R: 110, G: 85, B: 70, A: 255})
+		c.circle(sim.Vec2{X: sim.ShopkeeperPos.X, Y: sim.ShopkeeperPos.Y - 12}, 10, color.RGBA{R: 215, G: 190, B: 165, A: 255})
+	}
+	for _, o := range g.Offers {
+		col := pal.Offers[o.Kind]
+		if o.Purchased {
+			col = color.RGBA{R: 55, G: 50, B: 48, A: 255}
+		}
+		c.rect(o.Pos.X-14, o.Pos.Y-14, 28, 28, col)
+	}
+	for _, p := range g.Pickups {
+		if p.Active {
+			c.circle(p.Pos, 7, pal.Pickups[p.Kind])
+		}
+	}
+	for _, b := range g.BombList {
+		if b.Active {
+			c.circle(b.Pos, 8, color.RGBA{R: 55, G: 52, B: 50, A: 255})
+		}
+	}
+	for _, ex := range g.Explosions {
+		ratio := float64(ex.Timer) / sim.ExplosionTicks
+		c.circle(ex.Pos, ex.Radius, color.RGBA{R: 250, G: 170, B: 90, A: uint8(180 * ratio)})
+	}
+	c.circle(g.PlayerPos, sim.PlayerRadius, g.Character().Color)
+	for _, f := range g.Familiars {
+		c.circle(f.Pos, 8, color.RGBA{R: 235, G: 225, B: 200, A: 255})
+	}
+	for _, b := range g.Bullets {
+		if b.Active {
+			c.circle(b.Pos, g.Tune.BulletRadius*math.Sqrt(b.Scale), color.RGBA{R: 180, G: 220, B: 255, A: 255})
+		}
+	}
+	for _, bm := range g.Beams {
+		c.line(bm.From, bm.To, bm.Width, color.RGBA{R: 200, G: 30, B: 40, A: 200})
+	}
+	for _, s := range g.EnemyShots {
+		if !s.Active {
+			continue
+		}
+		if s.FromBoss {
+			c.circle(s.Pos, g.Tune.BossShotRadius, color.RGBA{R: 230, G: 110, B: 90, A: 255})
+		} else {
+			c.circle(s.Pos, g.Tune.EnemyShotRadius, color.RGBA{R: 210, G: 125, B: 95, A: 255})
+		}
+	}
+	for _, e := range g.Enemies {
+		if !e.Alive {
+			continue
+		}
+		r := g.Tune.EnemyRadius
+		if e.Kind == sim.EnemyBoss {
+			r = g.Tune.BossRadius
+		}
+		c.circle(e.Pos, r, sim.EnemyColor(e))
+		if e.Champion != sim.ChampionNone {
+			c.ring(e.Pos, r+4, 2, sim.EnemyColor(e))
+		}
+	}
+	if g.FloorCleared() {
+		c.circle(sim.Vec2{X: sim.ScreenW / 2, Y: sim.ScreenH / 2}, 18, color.RGBA{R: 120, G: 180, B: 220, A: 180})
+	}
+	if g.Curse == sim.CurseDarkness {
+		c.shade(0, 0, sim.ScreenW, sim.ScreenH, func(x, y float64) bool {
+			return math.Hypot(x-g.PlayerPos.X, y-g.PlayerPos.Y) > sim.DarknessRadius
+		}, color.RGBA{A: 235})
+	}
+	drawHUD(c, g)
+	return c.img
+}
+
+// drawHUD shows health as pips, then score, floor and run time.
+func drawHUD(c canvas, g *sim.Game) {
+	for i := 0; i < g.MaxHP+g.SoulHP; i++ {
+		col := color.RGBA{R: 70, G: 40, B: 40, A: 255}
+		switch {
+		case i >= g.MaxHP:
+			col = color.RGBA{R: 140, G: 170, B: 230, A: 255}
+		case i < g.PlayerHP:
+			col = color.RGBA{R: 220, G: 60, B: 70, A: 255}
+		}
+		for y := 4; y < 10; y++ {
+			for x := 4 + i*8; x < 10+i*8; x++ {
+				c.plot(x, y, col)
+			}
+		}
+	}
+	hud := color.RGBA{R: 235, G: 225, B: 210, A: 255}
+	c.text(g.Tr("hud.score", g.Score)+"  "+sim.FormatRunTime(g.RunTicks), 4, 24, hud)
+	c.text(g.Tr("hud.floor", g.Floor, g.CurrentRoomID+1, len(g.Rooms), g.AliveEnemyCount(), g.RunRank()), 4, 38, hud)
+}
@@ -0,0 +1,97 @@
+// Command headless renders a recorded replay to images without a window or
+// GPU, for CI: a numbered PNG sequence, an animated GIF of the end, or both.
+//
+//	go run ./headless -replay run.json -gif failure.gif
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"isaac/capture"
+	"isaac/sim"
+)
+
+type options struct {
+	outDir  string
+	gifPath string
+	every   int
+	last    float64
+	scale   float64
+	force   bool
+}
+
+func main() {
+	var o options
+	replayPath := flag.String("replay", "", "replay file to render")
+	flag.StringVar(&o.outDir, "out", "", "directory for a numbered PNG sequence")
+	flag.StringVar(&o.gifPath, "gif", "", "animated GIF to write")
+	flag.IntVar(&o.every, "every", 6, "simulation ticks between frames")
+	flag.Float64Var(&o.last, "last", 10, "seconds the GIF keeps from the end of the replay; 0 keeps all")
+	flag.Float64Var(&o.scale, "scale", 0.5, "frame size relative to 960x540")
+	flag.BoolVar(&o.force, "force", false, "render a replay of a cheated run, which will diverge")
+	flag.Parse()
+	if *replayPath == "" || (o.outDir == "" && o.gifPath == "") || o.every < 1 || o.scale <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	r, err := sim.LoadReplay(*replayPath)
+	if err != nil {
+		fail(err)
+	}
+	ticks, frames, err := renderReplay(r, o)
+	if err != nil {
+		fail(fmt.Errorf("%s: %w", *replayPath, err))
+	}
+	fmt.Printf("%d ticks, %d frames\n", ticks, frames)
+}
+
+// renderReplay plays r in a fresh game and writes a frame every o.every
+// ticks.
+func renderReplay(r sim.Replay, o options) (ticks, frames int, err error) {
+	if r.Cheated && !o.force {
+		return 0, 0, fmt.Errorf("the run used console commands, which replays do not record; -force renders it anyway")
+	}
+	if o.outDir != "" {
+		if err := os.MkdirAll(o.outDir, 0o755); err != nil {
+			return 0, 0, err
+		}
+	}
+	keep := r.Ticks() / o.every
+	if o.last > 0 {
+		keep = min(keep, int(o.last*sim.SimTPS)/o.every)
+	}
+	ring := capture.NewRing(max(keep, 1))
+
+	g := sim.NewGame()
+	sim.ApplyPalette(g.Settings.Palette)
+	g.PlayReplay(r, func() {
+		ticks++
+		if ticks%o.every != 0 || err != nil {
+			return
+		}
+		img := drawFrame(g, o.scale)
+		if o.outDir != "" {
+			err = capture.WritePNG(filepath.Join(o.outDir, fmt.Sprintf("frame_%05d.png", frames)), img)
+		}
+		if o.gifPath != "" {
+			ring.Push(img)
+		}
+		frames++
+	})
+	if err != nil {
+		return ticks, frames, err
+	}
+	if o.gifPath != "" {
+		err = capture.WriteGIF(o.gifPath, ring.Frames(), o.every*100/sim.SimTPS)
+	}
+	return ticks, frames, err
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "headless:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"isaac/sim"
+)
+
+// TestRenderReplay records a short run and renders it the way CI would:
+// one PNG per frame and a GIF cut to the last second.
+func TestRenderReplay(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	g := sim.NewGame()
+	g.StartRunWithSeed(9)
+	for i := 0; i < 120; i++ {
+		g.Step(sim.TickInput{Move: sim.Vec2{X: 1}, Aim: sim.Vec2{Y: -1}})
+	}
+	r := g.Replay()
+
+	o := options{outDir: filepath.Join(dir, "frames"), gifPath: filepath.Join(dir, "out.gif"), every: 6, last: 1, scale: 0.25}
+	ticks, frames, err := renderReplay(r, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticks != 120 || frames != 20 {
+		t.Fatalf("rendered %d ticks, %d frames; want 120, 20", ticks, frames)
+	}
+	pngs, _ := filepath.Glob(filepath.Join(o.outDir, "*.png"))
+	if len(pngs) != frames {
+		t.Errorf("%d PNGs written, want %d", len(pngs), frames)
+	}
+	f, err := os.Open(o.gifPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	anim, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != sim.SimTPS/o.every {
+		t.Errorf("GIF has %d frames, want the last second's %d", len(anim.Image), sim.SimTPS/o.every)
+	}
+
+	r.Cheated = true
+	if _, _, err := renderReplay(r, o); err == nil {
+		t.Error("a cheated replay rendered without -force")
+	}
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/bitmapfont/v3"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"isaac/sim"
+)
+
+const (
+	hudMargin      = 14
+	heartSize      = 16
+	heartsPerRow   = 10
+	itemStripSlots = 18
+)
+
+var hudFace = text.NewGoXFace(bitmapfont.Face)
+
+var (
+	hudTextColor   = color.RGBA{R: 235, G: 225, B: 210, A: 255}
+	hudDimColor    = color.RGBA{R: 170, G: 160, B: 150, A: 255}
+	hudPanelColor  = color.RGBA{R: 20, G: 16, B: 16, A: 170}
+	heartColor     = color.RGBA{R: 205, G: 45, B: 50, A: 255}
+	heartEmpty     = color.RGBA{R: 60, G: 30, B: 32, A: 255}
+	shieldColor    = color.RGBA{R: 120, G: 170, B: 235, A: 255}
+	soulHeartColor = color.RGBA{R: 140, G: 170, B: 230, A: 255}
+	goldColor      = color.RGBA{R: 250, G: 215, B: 60, A: 255}
+)
+
+// hudView maps the HUD's logical 960x540 coordinates onto the window. The
+// world image is letterboxed into the same rectangle, so widgets line up with
+// the room whatever the window size.
+type hudView struct {
+	dst   *ebiten.Image
+	x, y  float64
+	scale float64
+}
+
+func newHUDView(dst *ebiten.Image) hudView {
+	b := dst.Bounds()
+	sw, sh := float64(b.Dx()), float64(b.Dy())
+	s := math.Min(sw/sim.ScreenW, sh/sim.ScreenH)
+	return hudView{dst: dst, x: (sw - sim.ScreenW*s) / 2, y: (sh - sim.ScreenH*s) / 2, scale: s}
+}
+
+func (v hudView) px(x, y float64) (float32, float32) {
+	return float32(v.x + x*v.scale), float32(v.y + y*v.scale)
+}
+
+func (v hudView) rect(x, y, w, h float64, col color.Color) {
+	px, py := v.px(x, y)
+	vector.DrawFilledRect(v.dst, px, py, float32(w*v.scale), float32(h*v.scale), col, false)
+}
+
+func (v hudView) strokeRect(x, y, w, h, width float64, col color.Color) {
+	px, py := v.px(x, y)
+	vector.StrokeRect(v.dst, px, py, float32(w*v.scale), float32(h*v.scale), float32(width*v.scale), col, false)
+}
+
+func (v hudView) circle(x, y, r float64, col color.Color) {
+	px, py := v.px(x, y)
+	vector.DrawFilledCircle(v.dst, px, py, float32(r*v.scale), col, true)
+}
+
+func (v hudView) text(s string, x, y float64, col color.Color, align text.Align) {
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(x, y)
+	op.GeoM.Scale(v.scale, v.scale)
+	op.GeoM.Translate(v.x, v.y)
+	op.ColorScale.ScaleWithColor(col)
+	op.PrimaryAlign = align
+	text.Draw(v.dst, s, hudFace, op)
+}
+
+// banner draws centred text on a dark strip, for the transient messages.
+func (v hudView) banner(s string, y float64, col color.Color) {
+	w, _ := text.Measure(s, hudFace, 0)
+	v.rect(sim.ScreenW/2-w/2-6, y-3, w+12, 18, hudPanelColor)
+	v.text(s, sim.ScreenW/2, y, col, text.AlignCenter)
+}
+
+func (g *Game) drawHUD(screen *ebiten.Image) {
+	v := newHUDView(screen)
+	y := g.drawHearts(v, hudMargin, hudMargin)
+	y = g.drawCounters(v, hudMargin, y+6)
+	g.drawActiveCharge(v, hudMargin, y+4)
+	g.drawTrinketSlot(v, hudMargin, y+32)
+	g.drawScoreWidget(v)
+	if g.ShowMiniMap && !g.MapHidden() {
+		g.drawMiniMap(v)
+	}
+	g.drawBossHPBar(v)
+	g.drawItemStrip(v)
+	g.drawRunInfo(v)
+	if g.Settings.Speedrun {
+		g.drawSplits(v)
+	}
+	g.drawHUDMessages(v)
+	if g.ShowFullMap && !g.MapHidden() {
+		g.drawFullMap(v)
+	}
+	if g.Settings.AimMode == sim.AimMouse {
+		drawCrosshair(v, g.crosshair)
+	}
+	if g.showTuning {
+		g.drawTuningOverlay(v)
+	}
+	if g.consoleOpen {
+		g.drawConsole(v)
+	}
+}
+
+// drawHearts draws one heart per two HP, a half heart for an odd remainder,
+// then soul hearts and the shield charges. It returns the y below them.
+func (g *Game) drawHearts(v hudView, x, y float64) float64 {
+	hearts := (g.MaxHP + 1) / 2
+	blink := g.PlayerHP > 0 && g.PlayerHP <= sim.LowHPThreshold && g.flashOn(g.RunTicks, 20)
+	step := heartSize + 3.0
+	col, row := 0, 0
+	for i := 0; i < hearts; i++ {
+		col, row = i%heartsPerRow, i/heartsPerRow
+		hx, hy := x+float64(col)*step, y+float64(row)*step
+		fill := 0.0
+		switch hp := g.PlayerHP - i*2; {
+		case hp >= 2:
+			fill = 1
+		case hp == 1:
+			fill = 0.5
+		}
+		full := heartColor
+		if blink {
+			full = color.RGBA{R: 250, G: 120, B: 120, A: 255}
+		}
+		drawHeart(v, hx, hy, fill, full)
+	}
+	souls := (g.SoulHP + 1) / 2
+	for i := 0; i < souls; i++ {
+		n := hearts + i
+		col, row = n%heartsPerRow, n/heartsPerRow
+		fill := 1.0
+		if g.SoulHP-i*2 == 1 {
+			fill = 0.5
+		}
+		drawHeart(v, x+float64(col)*step, y+float64(row)*step, fill, soulHeartColor)
+	}
+	hearts += souls
+	for i := 0; i < g.MaxShieldCharges; i++ {
+		n := hearts + i
+		col, row = n%heartsPerRow, n/heartsPerRow
+		drawShield(v, x+float64(col)*step, y+float64(row)*step, i < g.ShieldCharges)
+	}
+	return y + float64(row+1)*step
+}
+
+func drawHeart(v hudView, x, y, fill float64, full color.RGBA) {
+	heartShape(v, v.dst, x, y, heartEmpty)
+	if fill <= 0 {
+		return
+	}
+	px, py := v.px(x, y)
+	w := float32(heartSize * v.scale * fill)
+	clip := v.dst.SubImage(image.Rect(int(px)-1, int(py)-1, int(px+w), int(py)+int(heartSize*v.scale)+2)).(*ebiten.Image)
+	heartShape(v, clip, x, y, full)
+}
+
+func heartShape(v hudView, dst *ebiten.Image, x, y float64, col color.Color) {
+	r := heartSize / 4.0
+	px, py := v.px(x, y)
+	s := float32(v.scale)
+	vector.DrawFilledCircle(dst, px+float32(r)*s, py+float32(r)*s, float32(r)*s, col, true)
+	vector.DrawFilledCircle(dst, px+float32(3*r)*s, py+float32(r)*s, float32(r)*s, col, true)
+	var p vector.Path
+	p.MoveTo(px, py+float32(r)*s)
+	p.LineTo(px+heartSize*s, py+float32(r)*s)
+	p.LineTo(px+heartSize/2*s, py+heartSize*s)
+	p.Close()
+	fillPath(dst, &p, col)
+}
+
+func drawShield(v hudView, x, y float64, charged bool) {
+	px, py := v.px(x, y)
+	s := float32(v.scale)
+	var p vector.Path
+	p.MoveTo(px+2*s, py+1*s)
+	p.LineTo(px+14*s, py+1*s)
+	p.LineTo(px+14*s, py+8*s)
+	p.LineTo(px+8*s, py+15*s)
+	p.LineTo(px+2*s, py+8*s)
+	p.Close()
+	col := color.RGBA{R: 45, G: 55, B: 75, A: 255}
+	if charged {
+		col = shieldColor
+	}
+	fillPath(v.dst, &p, col)
+}
+
+func fillPath(dst *ebiten.Image, p *vector.Path, col color.Color) {
+	vs, is := p.AppendVerticesAndIndicesForFilling(nil, nil)
+	drawPathTriangles(dst, vs, is, col)
+}
+
+func strokePath(dst *ebiten.Image, p *vector.Path, width float32, col color.Color) {
+	vs, is := p.AppendVerticesAndIndicesForStroke(nil, nil, &vector.StrokeOptions{Width: width})
+	drawPathTriangles(dst, vs, is, col)
+}
+
+func drawPathTriangles(dst *ebiten.Image, vs []ebiten.Vertex, is []uint16, col color.Color) {
+	r, g, b, a := col.RGBA()
+	for i := range vs {
+		vs[i].SrcX, vs[i].SrcY = 1, 1
+		vs[i].ColorR = float32(r) / 0xffff
+		vs[i].ColorG = float32(g) / 0xffff
+		vs[i].ColorB = float32(b) / 0xffff
+		vs[i].ColorA = float32(a) / 0xffff
+	}
+	dst.DrawTriangles(vs, is, whitePixel, &ebiten.DrawTrianglesOptions{AntiAlias: true})
+}
+
+var whitePixel = func() *ebiten.Image {
+	img := ebiten.NewImage(3, 3)
+	img.Fill(color.White)
+	return img.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
+}()
+
+// drawCounters draws the coin, bomb and key counters in a column and returns
+// the y below them.
+func (g *Game) drawCounters(v hudView, x, y float64) float64 {
+	rows := []struct {
+		icon   func(hudView, float64, float64)
+		count  int
+		golden bool
+	}{
+		{drawCoinIcon, g.Coins, false},
+		{drawBombIcon, g.Bombs, g.GoldenBomb},
+		{drawKeyIcon, g.Keys, g.GoldenKey},
+	}
+	for i, r := range rows {
+		ry := y + float64(i)*18
+		r.icon(v, x+7, ry+7)
+		col := hudTextColor
+		if r.golden {
+			col = goldColor
+		}
+		v.text(fmt.Sprintf("%02d", r.count), x+20, ry, col, text.AlignStart)
+	}
+	return y + float64(len(rows))*18
+}
+
+func drawCoinIcon(v hudView, x, y float64) {
+	v.circle(x, y, 6, color.RGBA{R: 230, G: 190, B: 70, A: 255})
+	v.circle(x, y, 3, color.RGBA{R: 250, G: 220, B: 120, A: 255})
+}
+
+func drawBombIcon(v hudView, x, y float64) {
+	v.circle(x, y+1, 6, color.RGBA{R: 60, G: 60, B: 66, A: 255})
+	v.rect(x+2, y-7, 2, 4, color.RGBA{R: 200, G: 150, B: 90, A: 255})
+	v.circle(x+3, y-8, 1.5, color.RGBA{R: 255, G: 200, B: 90, A: 255})
+}
+
+func drawKeyIcon(v hudView, x, y float64) {
+	col := color.RGBA{R: 200, G: 200, B: 210, A: 255}
+	v.circle(x-3, y, 4, col)
+	v.circle(x-3, y, 1.5, hudPanelColor)
+	v.rect(x, y-1, 8, 2.5, col)
+	v.rect(x+5, y, 2, 4, col)
+}
+
+func (g *Game) drawActiveCharge(v hudView, x, y float64) {
+	def, ok := sim.ActiveDefs[g.ActiveItem]
+	if !ok || g.ActiveItem == sim.ActiveNone {
+		return
+	}
+	v.text(g.Tr(def.Key), x, y, hudTextColor, text.AlignStart)
+	for i := 0; i < def.Charges; i++ {
+		col := color.RGBA{R: 60, G: 60, B: 60, A: 255}
+		if i < g.ActiveCharge {
+			col = color.RGBA{R: 120, G: 220, B: 110, A: 255}
+			if g.ActiveCharge >= def.Charges {
+				col = color.RGBA{R: 250, G: 235, B: 120, A: 255}
+			}
+		}
+		v.rect(x+float64(i)*9, y+16, 7, 6, col)
+	}
+}
+
+// drawScoreWidget shows score and best at the top centre, with the kill
+// streak multiplier and a bar for the time left before the streak resets.
+func (g *Game) drawScoreWidget(v hudView) {
+	v.text(g.Tr("hud.score", g.Score), sim.ScreenW/2, hudMargin, hudTextColor, text.AlignCenter)
+	v.text(g.Tr("hud.best", g.BestScore), sim.ScreenW/2, hudMargin+14, hudDimColor, text.AlignCenter)
+	if g.KillStreak < 2 {
+		return
+	}
+	v.text(g.Tr("hud.streak", g.KillStreak), sim.ScreenW/2, hudMargin+30, color.RGBA{R: 255, G: 200, B: 90, A: 255}, text.AlignCenter)
+	ratio := sim.Clamp(float64(g.StreakTick)/sim.StreakTimeoutTicks, 0, 1)
+	v.rect(sim.ScreenW/2-40, hudMargin+46, 80, 3, hudPanelColor)
+	v.rect(sim.ScreenW/2-40, hudMargin+46, 80*ratio, 3, color.RGBA{R: 255, G: 200, B: 90, A: 255})
+}
+
+// drawItemStrip shows the most recent passive items along the bottom left.
+func (g *Game) drawItemStrip(v hudView) {
+	items := g.CollectedItems
+	hidden := 0
+	if len(items) > itemStripSlots {
+		hidden = len(items) - itemStripSlots
+		items = items[hidden:]
+	}
+	y := float64(sim.ScreenH - hudMargin - 16)
+	x := float64(hudMargin)
+	if hidden > 0 {
+		v.text(fmt.Sprintf("+%d", hidden), x, y+2, hudDimColor, text.AlignStart)
+		x += 26
+	}
+	for i, it := range items {
+		ix := x + float64(i)*20
+		v.rect(ix, y, 16, 16, itemColor(it))
+		v.strokeRect(ix, y, 16, 16, 1.5, color.RGBA{R: 40, G: 30, B: 25, A: 255})
+	}
+}
+
+// drawRunInfo puts the floor, stats and run details in the bottom right.
+func (g *Game) drawRunInfo(v hudView) {
+	x := float64(sim.ScreenW - hudMargin)
+	y := float64(sim.ScreenH - hudMargin - 14)
+	lines := []string{
+		fmt.Sprintf("%s  %s  %s", g.Character().Name, g.ModeLabel(g.Mode), sim.FormatRunTime(g.RunTicks)),
+		g.Tr("hud.stats", g.ShotDamage, g.ShotCooldownBase, g.MoveSpeed, int(g.CritChance*100)),
+		g.Tr("hud.floor", g.Floor, g.CurrentRoomID+1, len(g.Rooms), g.AliveEnemyCount(), g.RunRank()),
+		g.Tr("hud.run", g.RunSeed, g.FloorGenName, g.RunsCompleted, g.Deaths),
+	}
+	if g.Cheated {
+		lines = append(lines, g.Tr("hud.cheats"))
+	}
+	for i, l := range lines {
+		v.text(l, x, y-float64(i)*14, hudDimColor, text.AlignEnd)
+	}
+}
+
+func (g *Game) drawHUDMessages(v hudView) {
+	y := 90.0
+	line := func(s string, col color.Color) {
+		v.banner(s, y, col)
+		y += 20
+	}
+	if g.RoomClear {
+		line(g.Tr("hud.room_clear"), hudTextColor)
+	}
+	if g.StatusTextTick > 0 {
+		line(g.StatusText, hudTextColor)
+	}
+	if g.CurrentRoom().Type == sim.RoomBoss {
+		switch {
+		case g.BossInPhase3():
+			line(g.Tr("hud.boss_phase", 3), heartColor)
+		case g.BossInPhase2():
+			line(g.Tr("hud.boss_phase", 2), heartColor)
+		}
+	}
+	if g.CurrentRoom().Type == sim.RoomShop {
+		line(g.Trn("hud.shop", 2+g.ShopRerolls), hudTextColor)
+		if g.ShopOnSale() {
+			line(g.Tr("hud.shop_sale"), goldColor)
+		}
+	}
+	if g.AllRoomsCleared() {
+		line(g.Tr("hud.dungeon_clear"), hudTextColor)
+	}
+	if g.RushComplete {
+		line(g.Tr("hud.rush_complete"), hudTextColor)
+	}
+	if g.FloorCleared() {
+		line(g.Tr("hud.descend"), hudTextColor)
+	}
+	if g.ItemTextTicks > 0 {
+		v.banner(g.LastItemText, sim.ScreenH-60, color.RGBA{R: 250, G: 235, B: 160, A: 255})
+	}
+	if g.Paused {
+		v.banner(g.Tr("hud.paused"), sim.ScreenH/2-20, hudTextColor)
+		v.banner(g.Tr("hud.controls"), sim.ScreenH/2, hudDimColor)
+	}
+	if g.PlayerHP <= 0 {
+		v.banner(g.Tr("hud.died"), sim.ScreenH/2, hudTextColor)
+	}
+}
+
+func (g *Game) drawBossHPBar(v hudView) {
+	if g.CurrentRoom().Type != sim.RoomBoss {
+		return
+	}
+	for _, e := range g.Enemies {
+		if e.Kind != sim.EnemyBoss || !e.Alive {
+			continue
+		}
+		barW, barH := 260.0, 12.0
+		x := sim.ScreenW/2 - barW/2
+		y := 70.0
+		v.text(g.Tr("hud.boss"), x-8, y, hudTextColor, text.AlignEnd)
+		v.rect(x, y, barW, barH, color.RGBA{R: 45, G: 25, B: 25, A: 255})
+		ratio := sim.Clamp(float64(e.HP)/float64(g.BossMaxHP(e)), 0, 1)
+		v.rect(x, y, barW*ratio, barH, color.RGBA{R: 180, G: 68, B: 60, A: 255})
+		v.strokeRect(x, y, barW, barH, 2, color.RGBA{R: 220, G: 175, B: 165, A: 255})
+		return
+	}
+}
+
+func drawCrosshair(v hudView, p sim.Vec2) {
+	col := color.RGBA{R: 240, G: 235, B: 220, A: 220}
+	px, py := v.px(p.X, p.Y)
+	r := float32(7 * v.scale)
+	vector.StrokeCircle(v.dst, px, py, r, float32(1.5*v.scale), col, true)
+	vector.StrokeLine(v.dst, px-2*r, py, px-r/2, py, float32(1.5*v.scale), col, true)
+	vector.StrokeLine(v.dst, px+r/2, py, px+2*r, py, float32(1.5*v.scale), col, true)
+	vector.StrokeLine(v.dst, px, py-2*r, px, py-r/2, float32(1.5*v.scale), col, true)
+	vector.StrokeLine(v.dst, px, py+r/2, px, py+2*r, float32(1.5*v.scale), col, true)
+}
@@ -0,0 +1,145 @@
+// Package i18n holds the game's message catalogs. Every language is an
+// embedded JSON file mapping message IDs to either a plain string or a
+// {"one", "other"} pair for counted messages; both are fmt format strings.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Default is the language used when the requested one is unknown and the
+// fallback for keys a catalog is missing.
+const Default = "en"
+
+//go:embed locales/*.json
+var files embed.FS
+
+// Message is one catalog entry. Plain strings unmarshal into both forms.
+type Message struct {
+	One   string `json:"one"`
+	Other string `json:"other"`
+}
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		m.One, m.Other = s, s
+		return nil
+	}
+	type plain Message
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	if p.One == "" || p.Other == "" {
+		return fmt.Errorf("plural message needs both one and other: %s", data)
+	}
+	*m = Message(p)
+	return nil
+}
+
+// pluralOne reports whether n takes the singular form. English and Italian
+// share the rule; languages with more forms would need a richer Message.
+var pluralOne = map[string]func(n int) bool{
+	"en": func(n int) bool { return n == 1 },
+	"it": func(n int) bool { return n == 1 },
+}
+
+type Catalog struct {
+	lang     string
+	msgs     map[string]Message
+	fallback map[string]Message
+}
+
+// Languages lists the embedded catalogs, sorted.
+func Languages() []string {
+	entries, _ := files.ReadDir("locales")
+	langs := make([]string, 0, len(entries))
+	for _, e := range entries {
+		langs = append(langs, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Messages returns the raw entries of one embedded catalog.
+func Messages(lang string) (map[string]Message, error) {
+	data, err := files.ReadFile(path.Join("locales", lang+".json"))
+	if err != nil {
+		return nil, err
+	}
+	msgs := map[string]Message{}
+	if err := json.Unmarshal(data, &msgs); err != nil {
+		return nil, fmt.Errorf("%s: %w", lang, err)
+	}
+	return msgs, nil
+}
+
+// Load returns the catalog for lang, falling back to Default when lang is
+// empty or unknown.
+func Load(lang string) *Catalog {
+	fallback, err := Messages(Default)
+	if err != nil {
+		panic(err)
+	}
+	c := &Catalog{lang: Default, msgs: fallback, fallback: fallback}
+	if lang == "" || lang == Default {
+		return c
+	}
+	if msgs, err := Messages(lang); err == nil {
+		c.lang, c.msgs = lang, msgs
+	}
+	return c
+}
+
+func (c *Catalog) Lang() string { return c.lang }
+
+func (c *Catalog) lookup(key string) (Message, bool) {
+	if m, ok := c.msgs[key]; ok {
+		return m, true
+	}
+	m, ok := c.fallback[key]
+	return m, ok
+}
+
+// T formats the message key with args. Unknown keys come back as the key
+// itself so a missing string is visible on screen rather than blank.
+func (c *Catalog) T(key string, args ...any) string {
+	m, ok := c.lookup(key)
+	if !ok {
+		return key
+	}
+	return format(m.Other, args)
+}
+
+// N picks the plural form for n and formats it. With no args, n itself is
+// the only argument, which covers messages like "Need %d coins to reroll".
+func (c *Catalog) N(key string, n int, args ...any) string {
+	m, ok := c.lookup(key)
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		args = []any{n}
+	}
+	one := pluralOne[c.lang]
+	if one == nil {
+		one = pluralOne[Default]
+	}
+	if one(n) {
+		return format(m.One, args)
+	}
+	return format(m.Other, args)
+}
+
+func format(s string, args []any) string {
+	if len(args) == 0 {
+		return s
+	}
+	return fmt.Sprintf(s, args...)
+}
@@ -0,0 +1,73 @@
+package i18n
+
+import (
+	"regexp"
+	"testing"
+)
+
+var verbRE = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z]`)
+
+// TestCatalogsComplete fails when a language is missing a key another
+// language has, or when a translation takes different arguments.
+func TestCatalogsComplete(t *testing.T) {
+	langs := Languages()
+	if len(langs) < 2 {
+		t.Fatalf("expected at least two languages, got %v", langs)
+	}
+	all := map[string]map[string]Message{}
+	keys := map[string]bool{}
+	for _, lang := range langs {
+		msgs, err := Messages(lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all[lang] = msgs
+		for k := range msgs {
+			keys[k] = true
+		}
+	}
+	ref := all[Default]
+	for _, lang := range langs {
+		for k := range keys {
+			m, ok := all[lang][k]
+			if !ok {
+				t.Errorf("%s: missing key %q", lang, k)
+				continue
+			}
+			want, ok := ref[k]
+			if !ok {
+				continue
+			}
+			if got, exp := verbRE.FindAllString(m.One+"|"+m.Other, -1), verbRE.FindAllString(want.One+"|"+want.Other, -1); len(got) != len(exp) {
+				t.Errorf("%s: key %q has %d format verbs, %s has %d", lang, k, len(got), Default, len(exp))
+			}
+		}
+	}
+}
+
+func TestPluralAndArgs(t *testing.T) {
+	en := Load("en")
+	if got := en.N("shop.need_reroll", 1); got != "Need 1 coin to reroll" {
+		t.Errorf("singular: %q", got)
+	}
+	if got := en.N("shop.need_reroll", 3); got != "Need 3 coins to reroll" {
+		t.Errorf("plural: %q", got)
+	}
+	it := Load("it")
+	if got := it.N("shop.need_reroll", 3); got != "Servono 3 monete per rinnovare" {
+		t.Errorf("italian plural: %q", got)
+	}
+	if got := it.T("floor.welcome", 2); got != "Benvenuto al piano 2" {
+		t.Errorf("italian args: %q", got)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	c := Load("xx")
+	if c.Lang() != Default {
+		t.Errorf("unknown language should fall back to %s, got %s", Default, c.Lang())
+	}
+	if got := c.T("no.such.key"); got != "no.such.key" {
+		t.Errorf("missing key: %q", got)
+	}
+}
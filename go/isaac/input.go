@@ -0,0 +1,127 @@
+package main
+
+import (
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"isaac/sim"
+)
+
+func (g *Game) pollInput() sim.TickInput {
+	aim, stick := g.aimInput()
+	in := sim.TickInput{
+		Move:      moveInput(),
+		Aim:       aim,
+		FireHeld:  ebiten.IsKeyPressed(ebiten.KeySpace),
+		AimAssist: stick,
+		Dash:      inpututil.IsKeyJustPressed(ebiten.KeyShiftLeft) || inpututil.IsKeyJustPressed(ebiten.KeyShiftRight),
+		Bomb:      inpututil.IsKeyJustPressed(ebiten.KeyE),
+		Active:    inpututil.IsKeyJustPressed(ebiten.KeyQ),
+		Chest:     inpututil.IsKeyJustPressed(ebiten.KeyG),
+		Buy:       inpututil.IsKeyJustPressed(ebiten.KeyF),
+		Reroll:    inpututil.IsKeyJustPressed(ebiten.KeyH),
+		Descend:   inpututil.IsKeyJustPressed(ebiten.KeyL),
+		Trinket:   inpututil.IsKeyJustPressed(ebiten.KeyT),
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if inpututil.IsGamepadButtonJustPressed(id, ebiten.GamepadButton0) {
+			in.Dash = true
+		}
+	}
+	return in
+}
+
+func moveInput() sim.Vec2 {
+	var dx, dy float64
+	if ebiten.IsKeyPressed(ebiten.KeyA) {
+		dx -= 1
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyD) {
+		dx += 1
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyW) {
+		dy -= 1
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyS) {
+		dy += 1
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		ax := ebiten.GamepadAxisValue(id, 0)
+		ay := ebiten.GamepadAxisValue(id, 1)
+		if math.Abs(ax) > 0.2 {
+			dx += ax
+		}
+		if math.Abs(ay) > 0.2 {
+			dy += ay
+		}
+	}
+	return sim.Vec2{X: dx, Y: dy}
+}
+
+// aimInput reads the fire direction. Arrow keys give eight directions, a
+// gamepad's right stick wins over them and, in mouse mode, holding the left
+// button aims at the crosshair. It also reports whether a stick was used, for
+// aim assist.
+func (g *Game) aimInput() (sim.Vec2, bool) {
+	dir := sim.Vec2{}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowUp) {
+		dir.Y--
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
+		dir.Y++
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
+		dir.X--
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
+		dir.X++
+	}
+	stick := false
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		ax := ebiten.GamepadAxisValue(id, 2)
+		ay := ebiten.GamepadAxisValue(id, 3)
+		if math.Hypot(ax, ay) > g.Settings.GamepadDeadzone {
+			dir = sim.Vec2{X: ax, Y: ay}
+			stick = true
+		}
+	}
+	if g.Settings.AimMode == sim.AimMouse {
+		g.crosshair = g.cursorPos()
+		if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+			dir = sim.Vec2{X: g.crosshair.X - g.PlayerPos.X, Y: g.crosshair.Y - g.PlayerPos.Y}
+			stick = false
+		}
+	}
+	l := math.Hypot(dir.X, dir.Y)
+	if l == 0 {
+		return sim.Vec2{}, false
+	}
+	return sim.Vec2{X: dir.X / l, Y: dir.Y / l}, stick
+}
+
+// cursorPos maps the mouse from window coordinates into the logical room,
+// undoing the letterbox scaling done by present.
+func (g *Game) cursorPos() sim.Vec2 {
+	mx, my := ebiten.CursorPosition()
+	s := math.Min(float64(g.outsideW)/sim.ScreenW, float64(g.outsideH)/sim.ScreenH)
+	if s <= 0 {
+		return sim.Vec2{X: float64(mx), Y: float64(my)}
+	}
+	ox := (float64(g.outsideW) - sim.ScreenW*s) / 2
+	oy := (float64(g.outsideH) - sim.ScreenH*s) / 2
+	return sim.Vec2{X: (float64(mx) - ox) / s, Y: (float64(my) - oy) / s}
+}
+
+// updateCursor hides the system cursor while playing in mouse mode; the HUD
+// draws a crosshair instead.
+func (g *Game) updateCursor() {
+	mode := ebiten.CursorModeVisible
+	if g.Settings.AimMode == sim.AimMouse && g.Scene == sim.ScenePlaying {
+		mode = ebiten.CursorModeHidden
+	}
+	if ebiten.CursorMode() != mode {
+		ebiten.SetCursorMode(mode)
+	}
+}
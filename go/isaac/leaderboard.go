@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"isaac/sim"
+)
+
+// boardStatusTime is how long an export message stays on the leaderboard.
+// No run is stepping there, so it is counted in wall time rather than sim
+// ticks.
+const boardStatusTime = 2 * time.Second
+
+func (g *Game) openLeaderboard() {
+	g.Scene = sim.SceneLeaderboard
+	g.boardMode = g.Mode
+	g.boardIndex = 0
+}
+
+func (g *Game) updateLeaderboardScene() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyB) || inpututil.IsKeyJustPressed(ebiten.KeyBackspace) {
+		g.Scene = sim.SceneCharacterSelect
+		return
+	}
+	modes := len(sim.RunModes)
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowLeft) || inpututil.IsKeyJustPressed(ebiten.KeyA) {
+		g.boardMode = sim.RunModes[(int(g.boardMode)+modes-1)%modes]
+		g.boardIndex = 0
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyArrowRight) || inpututil.IsKeyJustPressed(ebiten.KeyD) {
+		g.boardMode = sim.RunModes[(int(g.boardMode)+1)%modes]
+		g.boardIndex = 0
+	}
+	entries := g.Leaderboard[g.boardMode.String()]
+	if len(entries) > 0 {
+		if inpututil.IsKeyJustPressed(ebiten.KeyArrowUp) || inpututil.IsKeyJustPressed(ebiten.KeyW) {
+			g.boardIndex = (g.boardIndex + len(entries) - 1) % len(entries)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyArrowDown) || inpututil.IsKeyJustPressed(ebiten.KeyS) {
+			g.boardIndex = (g.boardIndex + 1) % len(entries)
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyX) {
+		if err := g.ExportLeaderboard(); err != nil {
+			g.boardStatus = g.Tr("board.export_failed", err)
+		} else {
+			g.boardStatus = g.Tr("board.exported", g.LeaderboardExportPath())
+		}
+		g.boardStatusUntil = time.Now().Add(boardStatusTime)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) && g.boardIndex < len(entries) {
+		e := entries[g.boardIndex]
+		g.Mode = g.boardMode
+		if _, ok := sim.CharacterByID(e.Character); ok {
+			g.CharacterID = e.Character
+		}
+		g.Scene = sim.ScenePlaying
+		g.StartRunWithSeed(e.Seed)
+	}
+}
+
+func (g *Game) drawLeaderboard(screen *ebiten.Image) {
+	screen.Fill(color.RGBA{R: 28, G: 24, B: 26, A: 255})
+	ebitenutil.DebugPrintAt(screen, g.Tr("board.title", g.ModeLabel(g.boardMode)), 60, 30)
+	ebitenutil.DebugPrintAt(screen, g.Tr("board.help"), 60, 50)
+	entries := g.Leaderboard[g.boardMode.String()]
+	if len(entries) == 0 {
+		ebitenutil.DebugPrintAt(screen, g.Tr("board.empty"), 60, 100)
+	}
+	for i, e := range entries {
+		cursor := " "
+		if i == g.boardIndex {
+			cursor = ">"
+		}
+		line := fmt.Sprintf("%s %2d. %6d  %s  F%-2d %-8s %s  %s  %s", cursor, i+1, e.Score, e.Rank, e.Floor, e.Character, g.Tr("board.seed", e.Seed), e.Date, e.Result)
+		ebitenutil.DebugPrintAt(screen, line, 60, 90+i*20)
+	}
+	if g.boardIndex < len(entries) {
+		items := entries[g.boardIndex].Items
+		text := g.Tr("board.no_items")
+		if len(items) > 0 {
+			text = g.Tr("board.items", strings.Join(items, ", "))
+		}
+		ebitenutil.DebugPrintAt(screen, text, 60, 110+sim.LeaderboardSize*20)
+	}
+	if time.Now().Before(g.boardStatusUntil) {
+		ebitenutil.DebugPrintAt(screen, g.boardStatus, 60, sim.ScreenH-40)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"isaac/sim"
+)
+
+const (
+	miniMapW    = 170
+	miniMapH    = 110
+	miniMapCell = 12
+	miniMapGap  = 4
+)
+
+type mapIcon int
+
+const (
+	mapIconNone mapIcon = iota
+	mapIconShop
+	mapIconBoss
+	mapIconTreasure
+	mapIconItem
+)
+
+// knownBounds is gridBounds over the rooms the fog of war lets through, so the
+// map's size gives nothing away either.
+func (g *Game) knownBounds() (int, int, int, int) {
+	cur := g.CurrentRoom()
+	minGX, minGY, maxGX, maxGY := cur.GridX, cur.GridY, cur.GridX, cur.GridY
+	for id, r := range g.Rooms {
+		if !g.RoomKnown(id) {
+			continue
+		}
+		minGX, maxGX = min(minGX, r.GridX), max(maxGX, r.GridX)
+		minGY, maxGY = min(minGY, r.GridY), max(maxGY, r.GridY)
+	}
+	return minGX, minGY, maxGX, maxGY
+}
+
+func (g *Game) roomIcon(id int) mapIcon {
+	room := g.Rooms[id]
+	switch room.Type {
+	case sim.RoomShop:
+		return mapIconShop
+	case sim.RoomBoss:
+		return mapIconBoss
+	case sim.RoomTreasure:
+		if !room.Reward.Taken {
+			return mapIconTreasure
+		}
+	}
+	cleared := g.VisitedRooms[id] && (id != g.CurrentRoomID || g.RoomClear)
+	if cleared && !room.Reward.Taken && room.Type != sim.RoomShop {
+		return mapIconItem
+	}
+	return mapIconNone
+}
+
+// drawMap draws the known rooms into the logical rectangle x,y,w,h. When the
+// layout is larger than the rectangle it pans to keep the current room in
+// view, clipping the rest.
+func (g *Game) drawMap(v hudView, x, y, w, h, cell, gap float64) {
+	minGX, minGY, maxGX, maxGY := g.knownBounds()
+	step := cell + gap
+	mw := float64(maxGX-minGX+1)*step - gap
+	mh := float64(maxGY-minGY+1)*step - gap
+	cur := g.CurrentRoom()
+	ox := panAxis(x, w, mw, float64(cur.GridX-minGX)*step+cell/2, true)
+	oy := panAxis(y, h, mh, float64(cur.GridY-minGY)*step+cell/2, false)
+
+	px0, py0 := v.px(x, y)
+	px1, py1 := v.px(x+w, y+h)
+	clip := v
+	clip.dst = v.dst.SubImage(image.Rect(int(px0), int(py0), int(math.Ceil(float64(px1))), int(math.Ceil(float64(py1))))).(*ebiten.Image)
+
+	cellPos := func(c [2]int) (float64, float64) {
+		return ox + float64(c[0]-minGX)*step, oy + float64(c[1]-minGY)*step
+	}
+	for _, d := range g.Doors {
+		if d.State == sim.DoorSecret || !g.RoomKnown(g.GridToRoomID[d.A]) || !g.RoomKnown(g.GridToRoomID[d.B]) {
+			continue
+		}
+		ax, ay := cellPos(d.A)
+		bx, by := cellPos(d.B)
+		pax, pay := v.px(ax+cell/2, ay+cell/2)
+		pbx, pby := v.px(bx+cell/2, by+cell/2)
+		vector.StrokeLine(clip.dst, pax, pay, pbx, pby, float32(math.Max(2, cell/4)*v.scale), doorMapColor(d.State), false)
+	}
+	for id, room := range g.Rooms {
+		if !g.RoomKnown(id) {
+			continue
+		}
+		rx, ry := cellPos([2]int{room.GridX, room.GridY})
+		col := color.RGBA{R: 48, G: 45, B: 43, A: 255}
+		if g.VisitedRooms[id] {
+			col = color.RGBA{R: 120, G: 112, B: 104, A: 255}
+		}
+		if id == g.CurrentRoomID {
+			col = color.RGBA{R: 175, G: 210, B: 145, A: 255}
+		}
+		clip.rect(rx, ry, cell, cell, col)
+		clip.strokeRect(rx, ry, cell, cell, 1, color.RGBA{R: 30, G: 24, B: 24, A: 255})
+		drawMapIcon(clip, g.roomIcon(id), rx+cell/2, ry+cell/2, cell)
+	}
+}
+
+// panAxis centres the focus point when the map overflows the area, clamped so
+// the map edge never pulls inside the area. A map that fits is aligned to the
+// start or the end of the area.
+func panAxis(start, size, content, focus float64, alignEnd bool) float64 {
+	if content <= size {
+		if alignEnd {
+			return start + size - content
+		}
+		return start
+	}
+	o := start + size/2 - focus
+	return sim.Clamp(o, start+size-content, start)
+}
+
+func drawMapIcon(v hudView, icon mapIcon, cx, cy, cell float64) {
+	s := cell / 12
+	switch icon {
+	case mapIconShop:
+		v.circle(cx, cy, 3.5*s, color.RGBA{R: 230, G: 190, B: 70, A: 255})
+		v.circle(cx, cy, 1.5*s, color.RGBA{R: 120, G: 90, B: 30, A: 255})
+	case mapIconBoss:
+		v.circle(cx, cy, 4*s, color.RGBA{R: 170, G: 45, B: 45, A: 255})
+		v.circle(cx-1.6*s, cy-0.8*s, 1*s, color.RGBA{R: 20, G: 10, B: 10, A: 255})
+		v.circle(cx+1.6*s, cy-0.8*s, 1*s, color.RGBA{R: 20, G: 10, B: 10, A: 255})
+	case mapIconTreasure:
+		px, py := v.px(cx, cy)
+		r := float32(4.5 * s * v.scale)
+		var p vector.Path
+		p.MoveTo(px, py-r)
+		p.LineTo(px+r, py)
+		p.LineTo(px, py+r)
+		p.LineTo(px-r, py)
+		p.Close()
+		fillPath(v.dst, &p, color.RGBA{R: 245, G: 215, B: 90, A: 255})
+	case mapIconItem:
+		v.rect(cx-2.5*s, cy-2.5*s, 5*s, 5*s, color.RGBA{R: 235, G: 235, B: 200, A: 255})
+	}
+}
+
+func (g *Game) drawMiniMap(v hudView) {
+	x := float64(sim.ScreenW - hudMargin - miniMapW)
+	y := float64(hudMargin)
+	v.rect(x-4, y-4, miniMapW+8, miniMapH+8, hudPanelColor)
+	g.drawMap(v, x, y, miniMapW, miniMapH, miniMapCell, miniMapGap)
+}
+
+// drawFullMap is the overlay shown while Tab is held. Cells are sized so the
+// whole layout fits the screen.
+func (g *Game) drawFullMap(v hudView) {
+	v.rect(0, 0, sim.ScreenW, sim.ScreenH, color.RGBA{R: 10, G: 8, B: 8, A: 215})
+	minGX, minGY, maxGX, maxGY := g.knownBounds()
+	cols, rows := float64(maxGX-minGX+1), float64(maxGY-minGY+1)
+	areaW, areaH := sim.ScreenW-160.0, sim.ScreenH-140.0
+	cell := math.Min(48, math.Min(areaW/(cols*1.3), areaH/(rows*1.3)))
+	gap := cell * 0.3
+	mw, mh := cols*(cell+gap)-gap, rows*(cell+gap)-gap
+	v.text(g.Tr("map.title", g.Floor), sim.ScreenW/2, 40, hudTextColor, text.AlignCenter)
+	g.drawMap(v, (sim.ScreenW-mw)/2, (sim.ScreenH-mh)/2+10, mw, mh, cell, gap)
+}
@@ -0,0 +1,48 @@
+-- Example mod: set "disabled" to false in mod.json to try it.
+
+isaac.register_item{
+  id = "vampire_tooth",
+  name = "Vampire Tooth",
+  on_collect = function()
+    local p = isaac.player()
+    isaac.set_stat("damage", p.damage + 1)
+  end,
+}
+
+isaac.register_enemy{
+  id = "ember",
+  base = "wander",
+  hp = 4,
+  weight = 0.1,
+  color = {240, 140, 60},
+  update = function(e)
+    if isaac.random(90) == 1 then
+      isaac.enemy_shot(e.x, e.y, 0, 3)
+      isaac.enemy_shot(e.x, e.y, 0, -3)
+    end
+  end,
+}
+
+local kills = 0
+
+function on_kill(ev)
+  kills = kills + 1
+  if kills % 25 == 0 then
+    isaac.give("coins", 5)
+    isaac.message("Bounty: 5 coins")
+  end
+end
+
+function on_hit(ev)
+  if ev.target == "enemy" and ev.kind == "example:ember" and ev.hp <= 0 then
+    isaac.shoot(ev.x, ev.y, 4, 0, 1)
+    isaac.shoot(ev.x, ev.y, -4, 0, 1)
+  end
+end
+
+function on_room_clear(ev)
+  isaac.log("cleared room", ev.room, "on floor", ev.floor)
+end
+
+function on_pickup(ev)
+end
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"isaac/sim"
+)
+
+func (g *Game) drawPickup(screen *ebiten.Image, p sim.Pickup) {
+	x, y := float32(p.Pos.X), float32(p.Pos.Y)
+	col := sim.CurrentPalette().Pickups[p.Kind]
+	outline, width := color.RGBA{R: 30, G: 25, B: 20, A: 255}, float32(1.5)
+	if sim.CurrentPalette().Width > 0 {
+		outline, width = sim.CurrentPalette().Outline, sim.CurrentPalette().Width
+	}
+	r := float32(g.Tune.PickupRadius)
+	switch p.Kind {
+	case sim.PickupHeartContainer:
+		r = float32(g.Tune.PickupRadius + 2)
+	case sim.PickupCoin:
+		r = float32(g.Tune.PickupRadius - 1)
+	case sim.PickupDime:
+		r = float32(g.Tune.PickupRadius + 1)
+	case sim.PickupBattery:
+		vector.DrawFilledRect(screen, x-5, y-8, 10, 16, col, false)
+		vector.DrawFilledRect(screen, x-2, y-10, 4, 2, color.RGBA{R: 200, G: 200, B: 200, A: 255}, false)
+		vector.StrokeRect(screen, x-5, y-8, 10, 16, width, outline, false)
+		return
+	case sim.PickupTrinket:
+		if sim.CurrentPalette().Width == 0 {
+			col = trinketColors[p.Trinket]
+		}
+		drawTrinketShape(screen, x, y, r+3, col, outline, width)
+		return
+	}
+	vector.DrawFilledCircle(screen, x, y, r, col, false)
+	vector.StrokeCircle(screen, x, y, r, width, outline, false)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"isaac/sim"
+)
+
+var rarityColors = map[sim.Rarity]color.RGBA{
+	sim.RarityCommon:   {R: 35, G: 28, B: 25, A: 255},
+	sim.RarityUncommon: {R: 90, G: 150, B: 220, A: 255},
+	sim.RarityRare:     {R: 240, G: 200, B: 70, A: 255},
+}
+
+func drawShopkeeper(dst *ebiten.Image) {
+	x, y := float32(sim.ShopkeeperPos.X), float32(sim.ShopkeeperPos.Y)
+	vector.DrawFilledCircle(dst, x, y+6, sim.ShopkeeperRadius, color.RGBA{R: 110, G: 85, B: 70, A: 255}, false)
+	vector.DrawFilledCircle(dst, x, y-12, 10, color.RGBA{R: 215, G: 190, B: 165, A: 255}, false)
+	vector.DrawFilledRect(dst, x-12, y-24, 24, 5, color.RGBA{R: 60, G: 45, B: 40, A: 255}, false)
+	vector.DrawFilledCircle(dst, x-3.5, y-13, 1.5, color.RGBA{R: 30, G: 20, B: 20, A: 255}, false)
+	vector.DrawFilledCircle(dst, x+3.5, y-13, 1.5, color.RGBA{R: 30, G: 20, B: 20, A: 255}, false)
+}
+
+func (g *Game) drawOffer(screen *ebiten.Image, o sim.ShopOffer) {
+	if o.Purchased {
+		vector.DrawFilledRect(screen, float32(o.Pos.X-14), float32(o.Pos.Y-14), 28, 28, color.RGBA{R: 55, G: 50, B: 48, A: 255}, false)
+		return
+	}
+	col := sim.CurrentPalette().Offers[o.Kind]
+	border := color.RGBA{R: 35, G: 28, B: 25, A: 255}
+	if sim.CurrentPalette().Width > 0 {
+		border = sim.CurrentPalette().Outline
+	}
+	switch {
+	case o.Kind == sim.OfferItem && g.Curse == sim.CurseBlind:
+		drawHiddenItem(screen, o.Pos, 28)
+	case o.Kind == sim.OfferItem:
+		col = itemColor(o.Item)
+		border = rarityColors[sim.ItemRarity[o.Item]]
+		fallthrough
+	default:
+		vector.DrawFilledRect(screen, float32(o.Pos.X-14), float32(o.Pos.Y-14), 28, 28, col, false)
+		vector.StrokeRect(screen, float32(o.Pos.X-14), float32(o.Pos.Y-14), 28, 28, 2, border, false)
+	}
+	label := fmt.Sprintf("%dc", g.OfferPrice(o))
+	switch {
+	case o.Free:
+		label = g.Tr("shop.free")
+	case o.Sale:
+		ebitenutil.DebugPrintAt(screen, g.Tr("shop.sale"), int(o.Pos.X)-14, int(o.Pos.Y)-32)
+	}
+	ebitenutil.DebugPrintAt(screen, label, int(o.Pos.X)-10, int(o.Pos.Y)+20)
+}
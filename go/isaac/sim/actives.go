@@ -0,0 +1,52 @@
+package sim
+
+type ActiveType int
+
+const (
+	ActiveNone ActiveType = iota
+	ActivePotion
+	ActiveBlastWave
+)
+
+type activeDef struct {
+	Key     string
+	Charges int
+}
+
+var ActiveDefs = map[ActiveType]activeDef{
+	ActiveNone:      {Key: "active.none"},
+	ActivePotion:    {Key: "active.potion", Charges: 4},
+	ActiveBlastWave: {Key: "active.blast_wave", Charges: 3},
+}
+
+func (g *Game) setActive(kind ActiveType) {
+	g.ActiveItem = kind
+	g.ActiveCharge = ActiveDefs[kind].Charges
+}
+
+func (g *Game) chargeActive(n int) {
+	g.ActiveCharge = minInt(ActiveDefs[g.ActiveItem].Charges, g.ActiveCharge+n)
+}
+
+func (g *Game) tryUseActive() {
+	if !g.input.Active || g.ActiveItem == ActiveNone {
+		return
+	}
+	def := ActiveDefs[g.ActiveItem]
+	if g.ActiveCharge < def.Charges {
+		g.StatusText = g.Tr("active.not_charged")
+		g.StatusTextTick = 60
+		return
+	}
+	g.ActiveCharge = 0
+	switch g.ActiveItem {
+	case ActivePotion:
+		g.PlayerHP = minInt(g.MaxHP, g.PlayerHP+2)
+		g.LastItemText = g.Tr("active.used_potion")
+	case ActiveBlastWave:
+		g.explode(g.PlayerPos, false)
+		g.LastItemText = g.Tr("active.used_blast_wave")
+	}
+	g.ItemTextTicks = itemTextDuration
+	g.emitEvent("active_use")
+}
@@ -0,0 +1,150 @@
+package sim
+
+import "image/color"
+
+type Scene int
+
+const (
+	ScenePlaying Scene = iota
+	SceneCharacterSelect
+	SceneLeaderboard
+	SceneEditor
+)
+
+type PassiveType int
+
+const (
+	PassiveNone PassiveType = iota
+	PassiveTwinBond
+	PassiveRegen
+	PassiveBargain
+	PassiveShieldRecharge
+	PassiveGlassCannon
+)
+
+// Character is a starting loadout. Zero-valued fields are real values, so
+// every stat the run resets is spelled out.
+type Character struct {
+	ID           string
+	Name         string
+	MaxHP        int
+	Speed        float64
+	Damage       int
+	DamageMult   float64
+	FireCooldown int
+	Crit         float64
+	Luck         float64
+	Bombs        int
+	Coins        int
+	Keys         int
+	Shield       int
+	Items        []ItemType
+	Active       ActiveType
+	Passive      PassiveType
+	Color        color.RGBA
+}
+
+var CharacterRoster = []Character{
+	{
+		ID: "isaac", Name: "Isaac",
+		MaxHP: playerMaxHP, Speed: playerSpeed, Damage: bulletDamage, DamageMult: 1, FireCooldown: fireCooldownTicks,
+		Crit: 0.08, Bombs: bombStartCount,
+		Color: color.RGBA{R: 220, G: 210, B: 190, A: 255},
+	},
+	{
+		ID: "gemini", Name: "Gemini",
+		MaxHP: playerMaxHP, Speed: playerSpeed, Damage: bulletDamage, DamageMult: 0.7, FireCooldown: fireCooldownTicks,
+		Crit: 0.05, Bombs: 1, Items: []ItemType{ItemMultiShot}, Passive: PassiveTwinBond,
+		Color: color.RGBA{R: 200, G: 215, B: 235, A: 255},
+	},
+	{
+		ID: "maggie", Name: "Maggie",
+		MaxHP: 8, Speed: 2.8, Damage: bulletDamage, DamageMult: 1, FireCooldown: fireCooldownTicks + 1,
+		Crit: 0.06, Bombs: 1, Active: ActivePotion, Passive: PassiveRegen,
+		Color: color.RGBA{R: 235, G: 190, B: 200, A: 255},
+	},
+	{
+		ID: "cain", Name: "Cain",
+		MaxHP: 4, Speed: 3.4, Damage: bulletDamage, DamageMult: 1.2, FireCooldown: fireCooldownTicks,
+		Crit: 0.10, Luck: 0.10, Bombs: 1, Keys: 1, Passive: PassiveBargain,
+		Color: color.RGBA{R: 200, G: 190, B: 150, A: 255},
+	},
+	{
+		ID: "bluebaby", Name: "???",
+		MaxHP: 4, Speed: 3.4, Damage: bulletDamage, DamageMult: 1, FireCooldown: fireCooldownTicks,
+		Crit: 0.08, Bombs: 2, Shield: 2, Active: ActiveBlastWave, Passive: PassiveShieldRecharge,
+		Color: color.RGBA{R: 150, G: 190, B: 230, A: 255},
+	},
+	{
+		ID: "judas", Name: "Judas",
+		MaxHP: 2, Speed: playerSpeed, Damage: bulletDamage, DamageMult: 1.35, FireCooldown: fireCooldownTicks,
+		Crit: 0.08, Bombs: bombStartCount, Coins: 3, Active: ActiveBlastWave, Passive: PassiveGlassCannon,
+		Color: color.RGBA{R: 210, G: 185, B: 120, A: 255},
+	},
+}
+
+func CharacterByID(id string) (Character, bool) {
+	for _, c := range CharacterRoster {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	return Character{}, false
+}
+
+func (g *Game) Character() Character {
+	if c, ok := CharacterByID(g.CharacterID); ok {
+		return c
+	}
+	return CharacterRoster[0]
+}
+
+func (g *Game) applyCharacter(c Character) {
+	g.MaxHP = c.MaxHP
+	g.PlayerHP = c.MaxHP
+	g.MoveSpeed = c.Speed
+	g.ShotDamage = c.Damage
+	g.damageMult = c.DamageMult
+	g.ShotCooldownBase = c.FireCooldown
+	g.CritChance = c.Crit
+	g.luck = c.Luck
+	g.Bombs = c.Bombs
+	g.Coins = c.Coins
+	g.Keys = c.Keys
+	g.MaxShieldCharges = c.Shield
+	g.ShieldCharges = c.Shield
+	g.setActive(c.Active)
+	for _, item := range c.Items {
+		g.grantItem(item)
+	}
+}
+
+// onRoomCleared runs once per room, when its last enemy dies.
+func (g *Game) onRoomCleared() {
+	g.chargeActive(1)
+	switch g.Character().Passive {
+	case PassiveRegen:
+		if g.PlayerHP <= g.MaxHP/2 {
+			g.PlayerHP = minInt(g.MaxHP, g.PlayerHP+1)
+		}
+	case PassiveShieldRecharge:
+		g.ShieldCharges = g.MaxShieldCharges
+	}
+	g.onTrinketRoomClear()
+	g.emitEvent("room_clear")
+	g.modHook("on_room_clear", map[string]any{"room": g.CurrentRoomID + 1, "floor": g.Floor, "template": g.CurrentRoom().Template})
+}
+
+func (g *Game) passiveDamageBonus() int {
+	if g.Character().Passive == PassiveGlassCannon && g.PlayerHP == g.MaxHP {
+		return 1
+	}
+	return 0
+}
+
+func (g *Game) shopPrice(base int) int {
+	if g.Character().Passive == PassiveBargain {
+		return maxInt(1, base-1)
+	}
+	return base
+}
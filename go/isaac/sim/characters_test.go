@@ -0,0 +1,22 @@
+package sim
+
+import "testing"
+
+// TestStartingItemsAreNotPickups checks that a character's starting items
+// take effect without counting as collected or showing pickup text.
+func TestStartingItemsAreNotPickups(t *testing.T) {
+	inTempDir(t)
+	g := NewGame()
+	g.CharacterID = "gemini"
+	g.StartRunWithSeed(4)
+	if !g.multiShot {
+		t.Error("Gemini starts without multi-shot")
+	}
+	if len(g.CollectedItems) != 0 || g.ItemTextTicks != 0 {
+		t.Errorf("starting items show as pickups: %v, text %q", g.CollectedItems, g.LastItemText)
+	}
+	isaac := CharacterRoster[0]
+	if isaac.Active != ActiveNone || isaac.Passive != PassiveNone {
+		t.Error("Isaac starts with an active or a passive")
+	}
+}
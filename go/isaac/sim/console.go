@@ -0,0 +1,174 @@
+package sim
+
+import (
+	"strconv"
+	"strings"
+)
+
+var consoleEnemies = map[string]EnemyType{
+	"chaser":  EnemyChaser,
+	"wander":  EnemyWander,
+	"shooter": EnemyShooter,
+	"dasher":  EnemyDasher,
+	"spawner": EnemySpawner,
+	"boss":    EnemyBoss,
+}
+
+// RunCommand executes one console command and returns its reply. Anything
+// that changes the run marks it as cheated; starting a seed is a fresh run.
+func (g *Game) RunCommand(args []string) string {
+	cmd := args[0]
+	switch cmd {
+	case "help":
+		return g.Tr("console.help")
+	case "seed":
+		if len(args) != 2 {
+			return g.Tr("console.usage", "seed <n>")
+		}
+		seed, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return g.Tr("console.usage", "seed <n>")
+		}
+		g.StartRunWithSeed(seed)
+		return g.Tr("console.seed", seed)
+	}
+	// The flag goes up before the cheat runs: a cheat can end the run, and
+	// whatever that saves must already count as cheated. Rejected commands
+	// change nothing and leave it as it was.
+	was := g.Cheated
+	g.Cheated = true
+	reply, ok := g.runCheat(args)
+	if !ok {
+		g.Cheated = was
+	}
+	return reply
+}
+
+func (g *Game) runCheat(args []string) (string, bool) {
+	switch args[0] {
+	case "give":
+		return g.cheatGive(args[1:])
+	case "spawn":
+		return g.cheatSpawn(args[1:])
+	case "goto":
+		if len(args) != 3 || args[1] != "room" {
+			return g.Tr("console.usage", "goto room <id>"), false
+		}
+		id, err := strconv.Atoi(args[2])
+		if err != nil || id < 1 || id > len(g.Rooms) {
+			return g.Tr("console.no_room", args[2]), false
+		}
+		g.swapRoom(id-1, Vec2{X: ScreenW / 2, Y: ScreenH / 2})
+		return g.Tr("console.room", id), true
+	case "floor":
+		n := 0
+		if len(args) == 2 {
+			n, _ = strconv.Atoi(args[1])
+		}
+		if n < 1 {
+			return g.Tr("console.usage", "floor <n>"), false
+		}
+		g.enterFloor(n)
+		return g.Tr("console.floor", g.Floor), true
+	case "god":
+		g.godMode = !g.godMode
+		if g.godMode {
+			return g.Tr("console.god_on"), true
+		}
+		return g.Tr("console.god_off"), true
+	case "killall":
+		n := 0
+		for i := range g.Enemies {
+			if e := &g.Enemies[i]; e.Alive {
+				e.Alive = false
+				g.onEnemyKilled(*e)
+				n++
+			}
+		}
+		g.CurrentRoom().Waves = nil
+		return g.Trn("console.killed", n), true
+	case "reveal":
+		g.hasCompass = true
+		for _, d := range g.Doors {
+			if d.State == DoorSecret {
+				d.State = DoorOpen
+			}
+		}
+		return g.Tr("console.revealed"), true
+	}
+	return g.Tr("console.unknown", args[0]), false
+}
+
+func (g *Game) cheatGive(args []string) (string, bool) {
+	if len(args) < 2 {
+		return g.Tr("console.usage", "give item <name> | give coins|bombs|keys <n>"), false
+	}
+	if args[0] == "item" {
+		name := strings.Join(args[1:], "")
+		for it := ItemDamage; it < itemTypeCount; it++ {
+			if strings.HasPrefix(strings.ToLower(strings.ReplaceAll(itemNames[it], " ", "")), name) {
+				g.applyItem(it)
+				return g.Tr("console.gave", itemNames[it]), true
+			}
+		}
+		return g.Tr("console.no_item", name), false
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil {
+		return g.Tr("console.usage", "give coins|bombs|keys <n>"), false
+	}
+	switch args[0] {
+	case "coins":
+		g.Coins = maxInt(0, g.Coins+n)
+	case "bombs":
+		g.Bombs = minInt(bombCap, maxInt(0, g.Bombs+n))
+	case "keys":
+		g.Keys = maxInt(0, g.Keys+n)
+	default:
+		return g.Tr("console.usage", "give item <name> | give coins|bombs|keys <n>"), false
+	}
+	return g.Tr("console.gave", args[1]+" "+args[0]), true
+}
+
+func (g *Game) cheatSpawn(args []string) (string, bool) {
+	if len(args) == 0 {
+		return g.Tr("console.usage", "spawn <enemy> [n]"), false
+	}
+	if _, ok := consoleEnemies[args[0]]; !ok && g.modEnemyByID(args[0]) == nil {
+		return g.Tr("console.no_enemy", args[0]), false
+	}
+	n := 1
+	if len(args) > 1 {
+		if v, err := strconv.Atoi(args[1]); err == nil && v > 0 {
+			n = minInt(v, 20)
+		}
+	}
+	for i := 0; i < n; i++ {
+		pos := Vec2{
+			X: RoomMargin + 60 + g.rng.Float64()*(ScreenW-2*RoomMargin-120),
+			Y: RoomMargin + 60 + g.rng.Float64()*(ScreenH-2*RoomMargin-120),
+		}
+		e, _ := g.enemyByName(args[0], pos)
+		g.Enemies = append(g.Enemies, g.readyEnemy(e))
+	}
+	return g.Trn("console.spawned", n), true
+}
+
+func (g *Game) newEnemyOfKind(kind EnemyType, pos Vec2) Enemy {
+	switch kind {
+	case EnemyBoss:
+		return g.newBoss(pos)
+	case EnemySpawner:
+		return g.newSpawner(pos, 0)
+	}
+	e := g.rollEnemy(pos, 0)
+	e.Kind = kind
+	e.ShootCooldown, e.WanderTimer = 0, 0
+	switch kind {
+	case EnemyShooter:
+		e.ShootCooldown = g.Tune.EnemyShotDelay
+	case EnemyDasher:
+		e.WanderTimer = 40 + g.rng.Intn(30)
+	}
+	return e
+}
@@ -0,0 +1,56 @@
+package sim
+
+import (
+	"os"
+	"testing"
+)
+
+// TestKillallFinishesRushCheated clears the last boss of a boss rush with
+// the console: the run completes but must not reach the leaderboard.
+func TestKillallFinishesRushCheated(t *testing.T) {
+	inTempDir(t)
+	g := NewGame()
+	g.Mode = ModeBossRush
+	g.StartRunWithSeed(3)
+	last := -1
+	for id, room := range g.Rooms {
+		if room.Type != RoomBoss {
+			continue
+		}
+		for i := range room.Enemies {
+			room.Enemies[i].Alive = false
+		}
+		last = max(last, id)
+	}
+	g.swapRoom(last, Vec2{X: ScreenW / 2, Y: ScreenH / 2})
+	for i := range g.Enemies {
+		g.Enemies[i].Alive = true
+	}
+	g.Score = 500
+
+	g.RunCommand([]string{"killall"})
+	if !g.RushComplete {
+		t.Fatal("killall on the last boss did not complete the rush")
+	}
+	if n := len(g.Leaderboard[ModeBossRush.String()]); n != 0 {
+		t.Errorf("cheated rush added %d leaderboard entries", n)
+	}
+}
+
+// TestFloorCheatRecordsNoClear jumps floors with the console: no floor was
+// cleared, so none may reach the telemetry or the splits.
+func TestFloorCheatRecordsNoClear(t *testing.T) {
+	inTempDir(t)
+	g := NewGame()
+	g.StartRunWithSeed(3)
+	g.RunCommand([]string{"floor", "4"})
+	if g.Floor != 4 {
+		t.Fatalf("on floor %d, want 4", g.Floor)
+	}
+	if g.floorsCleared != 0 || len(g.Splits) != 0 {
+		t.Errorf("%d floors cleared and %d splits after the jump", g.floorsCleared, len(g.Splits))
+	}
+	if _, err := os.Stat(g.telemetryPath()); !os.IsNotExist(err) {
+		t.Error("the jump wrote run telemetry")
+	}
+}
@@ -0,0 +1,180 @@
+package sim
+
+import "math"
+
+// Curse is a floor-wide modifier rolled when a new floor starts.
+type Curse int
+
+const (
+	CurseNone     Curse = iota
+	CurseDarkness       // only a circle around the player is lit
+	CurseLost           // no minimap or full map
+	CurseMaze           // doors sometimes lead to a random room
+	CurseBlind          // reward and shop items hide what they are
+)
+
+var curseNames = map[Curse]string{
+	CurseNone:     "none",
+	CurseDarkness: "darkness",
+	CurseLost:     "lost",
+	CurseMaze:     "maze",
+	CurseBlind:    "blind",
+}
+
+const (
+	curseBaseChance  = 0.2
+	curseFloorChance = 0.05
+	curseMaxChance   = 0.5
+	mazeChance       = 0.3
+)
+
+// DarknessRadius is how far from the player the darkness curse lets a
+// frontend show the room.
+const DarknessRadius = 130.0
+
+// FloorCurse records the curse a floor was played under, for telemetry.
+type FloorCurse struct {
+	Floor int    `json:"floor"`
+	Curse string `json:"curse"`
+}
+
+// TrinketType is the passive in the trinket slot. Only one is held at a time;
+// picking up another drops the old one where the player stands.
+type TrinketType int
+
+const (
+	TrinketNone           TrinketType = iota
+	TrinketSwallowedPenny             // drops a coin when hurt
+	TrinketRageStone                  // +1 damage at one heart or less
+	TrinketSteadyHand                 // faster fire while standing still
+	TrinketLuckyToe                   // extra drop on some room clears
+	TrinketBloodPenny                 // coins sometimes heal half a heart
+	trinketCount
+)
+
+var trinketNames = map[TrinketType]string{
+	TrinketSwallowedPenny: "swallowed_penny",
+	TrinketRageStone:      "rage_stone",
+	TrinketSteadyHand:     "steady_hand",
+	TrinketLuckyToe:       "lucky_toe",
+	TrinketBloodPenny:     "blood_penny",
+}
+
+const (
+	trinketDropTicks   = 60 // a dropped trinket cannot be picked up again before this
+	rageStoneHP        = 2
+	steadyHandCD       = 2
+	luckyToeChance     = 0.33
+	bloodPennyChance   = 0.25
+	swallowedPennyDist = 34.0
+)
+
+func (g *Game) CurseName(c Curse) string { return g.Tr("curse." + curseNames[c]) }
+
+func (g *Game) TrinketName(t TrinketType) string { return g.Tr("trinket." + trinketNames[t]) }
+
+// rollCurse picks the curse for a new floor. The first floor is never cursed
+// and the chance grows by floor up to curseMaxChance.
+func (g *Game) rollCurse() {
+	g.Curse = CurseNone
+	chance := math.Min(curseBaseChance+curseFloorChance*float64(g.Floor-2), curseMaxChance)
+	if g.Floor < 2 || g.rng.Float64() >= chance {
+		return
+	}
+	g.Curse = Curse(1 + g.rng.Intn(len(curseNames)-1))
+	g.curseLog = append(g.curseLog, FloorCurse{Floor: g.Floor, Curse: curseNames[g.Curse]})
+}
+
+// mazeRoom is where a door leads under the maze curse: usually where it
+// should, sometimes any other room that is neither secret, the boss room nor
+// behind a door still locked.
+func (g *Game) mazeRoom(next int) int {
+	if g.Curse != CurseMaze || g.rng.Float64() >= mazeChance {
+		return next
+	}
+	var ids []int
+	for _, r := range g.Rooms {
+		if r.ID != g.CurrentRoomID && r.ID != next && r.Type != RoomSecret && r.Type != RoomBoss && !g.roomLocked(r) {
+			ids = append(ids, r.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return next
+	}
+	g.StatusText = g.Tr("curse.maze_shift")
+	g.StatusTextTick = 90
+	return ids[g.rng.Intn(len(ids))]
+}
+
+func (g *Game) MapHidden() bool { return g.Curse == CurseLost }
+
+func (g *Game) rollTrinket() TrinketType {
+	t := TrinketType(1 + g.rng.Intn(int(trinketCount)-1))
+	if t == g.Trinket {
+		t = t%(trinketCount-1) + 1
+	}
+	return t
+}
+
+// takeTrinket puts t in the trinket slot and drops the one held before.
+func (g *Game) takeTrinket(t TrinketType) {
+	g.dropTrinket()
+	g.Trinket = t
+	g.trinketLog = append(g.trinketLog, trinketNames[t])
+	g.LastItemText = g.Tr("pickup.trinket", g.TrinketName(t))
+}
+
+func (g *Game) dropTrinket() {
+	if g.Trinket == TrinketNone {
+		return
+	}
+	g.Pickups = append(g.Pickups, Pickup{Pos: g.PlayerPos, Kind: PickupTrinket, Trinket: g.Trinket, Active: true})
+	g.Trinket = TrinketNone
+	g.trinketDropCD = trinketDropTicks
+}
+
+func (g *Game) tryDropTrinket() {
+	if g.input.Trinket && g.Trinket != TrinketNone {
+		g.LastItemText = g.Tr("trinket.dropped", g.TrinketName(g.Trinket))
+		g.ItemTextTicks = itemTextDuration
+		g.dropTrinket()
+	}
+}
+
+func (g *Game) trinketDamageBonus() int {
+	if g.Trinket == TrinketRageStone && g.PlayerHP <= rageStoneHP {
+		return 1
+	}
+	return 0
+}
+
+func (g *Game) trinketCooldown(cd int) int {
+	if g.Trinket == TrinketSteadyHand && g.input.Move == (Vec2{}) {
+		return maxInt(2, cd-steadyHandCD)
+	}
+	return cd
+}
+
+func (g *Game) onTrinketHurt() {
+	if g.Trinket != TrinketSwallowedPenny || g.PlayerHP == 0 {
+		return
+	}
+	a := g.rng.Float64() * 2 * math.Pi
+	pos := Vec2{
+		X: Clamp(g.PlayerPos.X+math.Cos(a)*swallowedPennyDist, RoomMargin+16, ScreenW-RoomMargin-16),
+		Y: Clamp(g.PlayerPos.Y+math.Sin(a)*swallowedPennyDist, RoomMargin+16, ScreenH-RoomMargin-16),
+	}
+	g.spawnDrop(dropEntry{Kind: PickupCoin, Count: 1}, pos)
+}
+
+func (g *Game) onTrinketRoomClear() {
+	if g.Trinket == TrinketLuckyToe && g.rng.Float64() < luckyToeChance {
+		g.dropLoot(Vec2{X: ScreenW / 2, Y: ScreenH/2 + 50}, true)
+	}
+}
+
+func (g *Game) onTrinketCoin() {
+	if g.Trinket == TrinketBloodPenny && g.PlayerHP < g.MaxHP && g.rng.Float64() < bloodPennyChance {
+		g.PlayerHP++
+	}
+}
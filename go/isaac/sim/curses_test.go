@@ -0,0 +1,27 @@
+package sim
+
+import "testing"
+
+// TestMazeSkipsLockedRooms checks that the maze curse never carries the player
+// past a treasure door that still wants a key.
+func TestMazeSkipsLockedRooms(t *testing.T) {
+	inTempDir(t)
+	g := NewGame()
+	g.StartRunWithSeed(11)
+	g.startNextFloor()
+	treasure := -1
+	for _, r := range g.Rooms {
+		if r.Type == RoomTreasure {
+			treasure = r.ID
+		}
+	}
+	if treasure < 0 || !g.roomLocked(g.Rooms[treasure]) {
+		t.Fatalf("floor %d has no locked treasure room", g.Floor)
+	}
+	g.Curse = CurseMaze
+	for i := 0; i < 500; i++ {
+		if g.mazeRoom(g.CurrentRoomID) == treasure {
+			t.Fatal("the maze led into the locked treasure room")
+		}
+	}
+}
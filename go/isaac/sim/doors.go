@@ -0,0 +1,179 @@
+package sim
+
+import "math/rand"
+
+type DoorState int
+
+const (
+	DoorOpen DoorState = iota
+	DoorClosed
+	DoorLocked
+	DoorSecret
+	DoorBoss
+)
+
+// Door joins two adjacent grid cells. Closed is never stored: it is the state
+// an open door shows while the current room still has enemies.
+type Door struct {
+	A     [2]int
+	B     [2]int
+	State DoorState
+	Boss  bool
+}
+
+func doorKey(a, b [2]int) [4]int {
+	if a[0] > b[0] || (a[0] == b[0] && a[1] > b[1]) {
+		a, b = b, a
+	}
+	return [4]int{a[0], a[1], b[0], b[1]}
+}
+
+func (g *Game) initDoors() {
+	g.Doors = make(map[[4]int]*Door)
+	for _, room := range g.Rooms {
+		a := [2]int{room.GridX, room.GridY}
+		for _, d := range LayoutDirs {
+			b := [2]int{a[0] + d[0], a[1] + d[1]}
+			otherID, ok := g.GridToRoomID[b]
+			if !ok {
+				continue
+			}
+			key := doorKey(a, b)
+			if _, exists := g.Doors[key]; exists {
+				continue
+			}
+			door := &Door{A: a, B: b, State: DoorOpen}
+			other := g.Rooms[otherID]
+			switch {
+			case room.Type == RoomSecret || other.Type == RoomSecret:
+				door.State = DoorSecret
+			case room.Type == RoomBoss || other.Type == RoomBoss:
+				door.State = DoorBoss
+				door.Boss = true
+			case (room.Type == RoomTreasure || other.Type == RoomTreasure) && g.Floor > 1:
+				door.State = DoorLocked
+			}
+			g.Doors[key] = door
+		}
+	}
+}
+
+// pickSecretCell finds an empty cell touching at least two rooms, away from
+// the boss and treasure rooms so those stay dead ends.
+func pickSecretCell(plan floorPlan, rng *rand.Rand) ([2]int, bool) {
+	occupied := cellSet(plan.Cells)
+	candidates := make([][2]int, 0)
+	seen := map[[2]int]bool{}
+	for _, c := range plan.Cells {
+		for _, d := range LayoutDirs {
+			next := [2]int{c[0] + d[0], c[1] + d[1]}
+			if occupied[next] || seen[next] || !inLayoutBounds(next) {
+				continue
+			}
+			seen[next] = true
+			if neighbourCount(occupied, next) < 2 || cellsAdjacent(next, plan.Boss) || cellsAdjacent(next, plan.Treasure) {
+				continue
+			}
+			candidates = append(candidates, next)
+		}
+	}
+	if len(candidates) == 0 {
+		return [2]int{}, false
+	}
+	return candidates[rng.Intn(len(candidates))], true
+}
+
+func (g *Game) DoorToward(dx, dy int) *Door {
+	cur := g.CurrentRoom()
+	a := [2]int{cur.GridX, cur.GridY}
+	return g.Doors[doorKey(a, [2]int{a[0] + dx, a[1] + dy})]
+}
+
+func (g *Game) EffectiveDoorState(d *Door) DoorState {
+	if d.State == DoorSecret {
+		return DoorSecret
+	}
+	if !g.RoomClear {
+		return DoorClosed
+	}
+	return d.State
+}
+
+// roomLocked reports whether a door into r is still locked.
+func (g *Game) roomLocked(r *Room) bool {
+	a := [2]int{r.GridX, r.GridY}
+	for _, d := range LayoutDirs {
+		if door := g.Doors[doorKey(a, [2]int{a[0] + d[0], a[1] + d[1]})]; door != nil && door.State == DoorLocked {
+			return true
+		}
+	}
+	return false
+}
+
+// tryPassDoor reports whether the player may walk through d right now,
+// spending a key on locked doors.
+func (g *Game) tryPassDoor(d *Door) bool {
+	switch g.EffectiveDoorState(d) {
+	case DoorOpen:
+		return true
+	case DoorLocked:
+		if !g.spendKey() {
+			g.StatusText = g.Tr("door.need_key")
+			g.StatusTextTick = 80
+			return false
+		}
+		d.State = DoorOpen
+		g.StatusText = g.Tr("door.unlocked")
+		g.StatusTextTick = 80
+		g.emitEvent("door_unlock")
+	case DoorBoss:
+		g.StatusText = g.Tr("door.boss_sealed")
+		g.StatusTextTick = 80
+	}
+	return false
+}
+
+// updateDoors unseals boss doors once every other room has been cleared.
+func (g *Game) updateDoors() {
+	if g.bossDoorsOpen || !g.allNonBossRoomsCleared() {
+		return
+	}
+	g.bossDoorsOpen = true
+	for _, d := range g.Doors {
+		if d.State == DoorBoss {
+			d.State = DoorOpen
+		}
+	}
+	g.StatusText = g.Tr("door.boss_open")
+	g.StatusTextTick = 120
+	g.emitEvent("boss_door_open")
+}
+
+// revealSecretDoors opens secret doors of the current room caught in a blast.
+func (g *Game) revealSecretDoors(pos Vec2, radius float64) {
+	for _, d := range LayoutDirs {
+		door := g.DoorToward(d[0], d[1])
+		if door == nil || door.State != DoorSecret {
+			continue
+		}
+		if Distance(pos, DoorCenter(d[0], d[1])) <= radius+DoorHalf {
+			door.State = DoorOpen
+			g.StatusText = g.Tr("door.secret")
+			g.StatusTextTick = 100
+			g.emitEvent("secret_door")
+		}
+	}
+}
+
+func DoorCenter(dx, dy int) Vec2 {
+	switch {
+	case dx < 0:
+		return Vec2{X: RoomMargin, Y: ScreenH / 2}
+	case dx > 0:
+		return Vec2{X: ScreenW - RoomMargin, Y: ScreenH / 2}
+	case dy < 0:
+		return Vec2{X: ScreenW / 2, Y: RoomMargin}
+	default:
+		return Vec2{X: ScreenW / 2, Y: ScreenH - RoomMargin}
+	}
+}
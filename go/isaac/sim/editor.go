@@ -0,0 +1,33 @@
+package sim
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+func RoomTemplatesPath() string { return filepath.Join(".", "room_templates.json") }
+
+// LoadRoomTemplates reads room_templates.json. When the file is there it
+// replaces the built-in templates; templates without enemy slots are dropped
+// since a combat room cannot be filled from them.
+func LoadRoomTemplates() []RoomTemplate {
+	data, err := os.ReadFile(RoomTemplatesPath())
+	if err != nil {
+		return builtinRoomTemplates()
+	}
+	var list []RoomTemplate
+	if err := json.Unmarshal(data, &list); err != nil {
+		return builtinRoomTemplates()
+	}
+	out := list[:0]
+	for _, t := range list {
+		if len(t.EnemySlots) > 0 {
+			out = append(out, t)
+		}
+	}
+	if len(out) == 0 {
+		return builtinRoomTemplates()
+	}
+	return out
+}
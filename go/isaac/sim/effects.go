@@ -0,0 +1,164 @@
+package sim
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+)
+
+const (
+	maxParticles    = 512
+	maxRoomDecals   = 64
+	glintEveryTicks = 24
+)
+
+type Particle struct {
+	Pos     Vec2
+	Vel     Vec2
+	Life    int
+	MaxLife int
+	Size    float32
+	Drag    float64
+	Col     color.RGBA
+	Active  bool
+}
+
+// Decal is a floor mark that stays in its room for the rest of the floor.
+type Decal struct {
+	Pos Vec2
+	R   float32
+	Col color.RGBA
+}
+
+// ParticlePool is a fixed ring of particles. When it is full the oldest slot
+// is reused, so effects never allocate and never grow without bound. It has
+// its own RNG so cosmetic randomness never shifts the simulation RNG.
+type ParticlePool struct {
+	items [maxParticles]Particle
+	next  int
+	rng   *rand.Rand
+}
+
+func (p *ParticlePool) reset(seed int64) {
+	p.clear()
+	p.rng = rand.New(rand.NewSource(seed))
+}
+
+func (p *ParticlePool) clear() {
+	for i := range p.items {
+		p.items[i].Active = false
+	}
+	p.next = 0
+}
+
+// Particles is the whole pool, live and spent; frontends draw the Active ones.
+func (p *ParticlePool) Particles() []Particle { return p.items[:] }
+
+func (p *ParticlePool) spawn(pt Particle) {
+	pt.Active = true
+	pt.MaxLife = pt.Life
+	p.items[p.next] = pt
+	p.next = (p.next + 1) % maxParticles
+}
+
+func (p *ParticlePool) burst(pos Vec2, n int, speed float64, life int, size float32, col color.RGBA) {
+	for i := 0; i < n; i++ {
+		a := p.rng.Float64() * 2 * math.Pi
+		s := speed * (0.4 + p.rng.Float64()*0.6)
+		p.spawn(Particle{
+			Pos:  pos,
+			Vel:  Vec2{X: math.Cos(a) * s, Y: math.Sin(a) * s},
+			Life: life/2 + p.rng.Intn(life/2+1),
+			Size: size,
+			Drag: 0.9,
+			Col:  col,
+		})
+	}
+}
+
+func (p *ParticlePool) update() {
+	for i := range p.items {
+		pt := &p.items[i]
+		if !pt.Active {
+			continue
+		}
+		pt.Life--
+		if pt.Life <= 0 {
+			pt.Active = false
+			continue
+		}
+		pt.Pos.X += pt.Vel.X
+		pt.Pos.Y += pt.Vel.Y
+		pt.Vel.X *= pt.Drag
+		pt.Vel.Y *= pt.Drag
+	}
+}
+
+func (g *Game) spawnHitSparks(pos Vec2) {
+	g.FX.burst(pos, 5, 2.6, 12, 2, color.RGBA{R: 255, G: 235, B: 170, A: 255})
+}
+
+func (g *Game) spawnDeathBurst(e Enemy) {
+	n, size := 14, float32(3)
+	if e.Kind == EnemyBoss {
+		n, size = 40, 4
+	}
+	g.FX.burst(e.Pos, n, 3.4, 30, size, EnemyColor(e))
+	g.addBloodSplat(e.Pos, e.Kind == EnemyBoss)
+}
+
+func (g *Game) addBloodSplat(pos Vec2, big bool) {
+	room := g.CurrentRoom()
+	drops := 3
+	spread := 10.0
+	if big {
+		drops, spread = 7, 26
+	}
+	for i := 0; i < drops; i++ {
+		d := Decal{
+			Pos: Vec2{X: pos.X + (g.FX.rng.Float64()*2-1)*spread, Y: pos.Y + (g.FX.rng.Float64()*2-1)*spread},
+			R:   float32(3 + g.FX.rng.Float64()*6),
+			Col: color.RGBA{R: 110 + uint8(g.FX.rng.Intn(30)), G: 22, B: 24, A: 170},
+		}
+		room.Decals = append(room.Decals, d)
+	}
+	if len(room.Decals) > maxRoomDecals {
+		room.Decals = room.Decals[len(room.Decals)-maxRoomDecals:]
+	}
+}
+
+func (g *Game) spawnDashTrail() {
+	col := g.Character().Color
+	col.A = 150
+	g.FX.spawn(Particle{Pos: g.PlayerPos, Life: 14, Size: PlayerRadius * 0.8, Drag: 1, Col: col})
+}
+
+// updateEffects advances particles one tick and sprinkles glints on items and
+// pickups lying in the room.
+func (g *Game) updateEffects() {
+	g.FX.update()
+	if g.RunTicks%glintEveryTicks != 0 {
+		return
+	}
+	spots := make([]Vec2, 0, len(g.Pickups)+1)
+	for _, p := range g.Pickups {
+		if p.Active {
+			spots = append(spots, p.Pos)
+		}
+	}
+	if room := g.CurrentRoom(); g.RoomClear && !room.Reward.Taken {
+		spots = append(spots, room.Reward.Pos)
+	}
+	if len(spots) == 0 {
+		return
+	}
+	s := spots[g.FX.rng.Intn(len(spots))]
+	g.FX.spawn(Particle{
+		Pos:  Vec2{X: s.X + (g.FX.rng.Float64()*2-1)*8, Y: s.Y + (g.FX.rng.Float64()*2-1)*8},
+		Vel:  Vec2{Y: -0.35},
+		Life: 20,
+		Size: 2,
+		Drag: 1,
+		Col:  color.RGBA{R: 255, G: 250, B: 215, A: 230},
+	})
+}
@@ -0,0 +1,198 @@
+package sim
+
+import "math"
+
+type FamiliarKind int
+
+const (
+	FamiliarBuddy  FamiliarKind = iota // follows, fires where the player fires
+	FamiliarSentry                     // follows, fires at the nearest enemy
+	FamiliarHalo                       // orbits, blocks shots and hurts on contact
+	FamiliarMagnet                     // follows, fetches pickups
+)
+
+const (
+	FamiliarRadius    = 7
+	familiarGap       = 24
+	familiarSpeed     = 3.0
+	buddyShotDelay    = 30
+	sentryShotDelay   = 45
+	sentryRange       = 300
+	familiarShotSpeed = 6.0
+	familiarDamage    = 1
+	orbitRadius       = 42
+	orbitSpeed        = 0.06
+	orbitHitDelay     = 12
+	orbitDamage       = 1
+)
+
+// Familiar is a companion granted by an item. Familiars are part of the run
+// state: they survive room swaps and floors and only go away on a new run.
+type Familiar struct {
+	Kind     FamiliarKind
+	Pos      Vec2
+	Prev     Vec2
+	Cooldown int
+}
+
+var FamiliarItems = map[ItemType]FamiliarKind{
+	ItemBuddy:  FamiliarBuddy,
+	ItemSentry: FamiliarSentry,
+	ItemHalo:   FamiliarHalo,
+	ItemMagnet: FamiliarMagnet,
+}
+
+var familiarItemKeys = map[ItemType]string{
+	ItemBuddy:  "item.buddy",
+	ItemSentry: "item.sentry",
+	ItemHalo:   "item.halo",
+	ItemMagnet: "item.magnet",
+}
+
+var familiarNames = map[FamiliarKind]string{
+	FamiliarBuddy:  "buddy",
+	FamiliarSentry: "sentry",
+	FamiliarHalo:   "halo",
+	FamiliarMagnet: "magnet",
+}
+
+func orbital(k FamiliarKind) bool { return k == FamiliarHalo }
+
+func (g *Game) addFamiliar(k FamiliarKind) {
+	g.Familiars = append(g.Familiars, Familiar{Kind: k, Pos: g.PlayerPos, Prev: g.PlayerPos})
+}
+
+// placeFamiliars drops every familiar on the player, for room entry.
+func (g *Game) placeFamiliars() {
+	for i := range g.Familiars {
+		g.Familiars[i].Pos = g.PlayerPos
+		g.Familiars[i].Prev = g.PlayerPos
+	}
+}
+
+func (g *Game) updateFamiliars() {
+	leader := g.PlayerPos
+	orbitals := 0
+	for _, f := range g.Familiars {
+		if orbital(f.Kind) {
+			orbitals++
+		}
+	}
+	slot := 0
+	for i := range g.Familiars {
+		f := &g.Familiars[i]
+		if f.Cooldown > 0 {
+			f.Cooldown--
+		}
+		if orbital(f.Kind) {
+			a := float64(g.RunTicks)*orbitSpeed + 2*math.Pi*float64(slot)/float64(orbitals)
+			f.Pos = Vec2{X: g.PlayerPos.X + math.Cos(a)*orbitRadius, Y: g.PlayerPos.Y + math.Sin(a)*orbitRadius}
+			slot++
+			g.updateHalo(f)
+			continue
+		}
+		if f.Kind == FamiliarMagnet && g.fetchPickup(f) {
+			continue
+		}
+		moveToward(&f.Pos, leader, familiarGap)
+		leader = f.Pos
+		switch f.Kind {
+		case FamiliarBuddy:
+			if dir := g.fireDir(); f.Cooldown == 0 && dir != (Vec2{}) {
+				g.familiarShoot(f, dir)
+				f.Cooldown = buddyShotDelay
+			}
+		case FamiliarSentry:
+			if t := g.nearestEnemy(f.Pos, sentryRange); f.Cooldown == 0 && t != nil {
+				d := Vec2{X: t.Pos.X - f.Pos.X, Y: t.Pos.Y - f.Pos.Y}
+				l := math.Max(1e-6, math.Hypot(d.X, d.Y))
+				g.familiarShoot(f, Vec2{X: d.X / l, Y: d.Y / l})
+				f.Cooldown = sentryShotDelay
+			}
+		}
+	}
+}
+
+// moveToward walks pos towards target at familiar speed, stopping gap short.
+func moveToward(pos *Vec2, target Vec2, gap float64) {
+	d := Distance(*pos, target)
+	if d <= gap {
+		return
+	}
+	step := math.Min(familiarSpeed*math.Max(1, d/60), d-gap)
+	pos.X += (target.X - pos.X) / d * step
+	pos.Y += (target.Y - pos.Y) / d * step
+}
+
+func (g *Game) nearestEnemy(from Vec2, within float64) *Enemy {
+	var best *Enemy
+	for i := range g.Enemies {
+		if e := &g.Enemies[i]; e.Alive && Distance(from, e.Pos) < within {
+			best, within = e, Distance(from, e.Pos)
+		}
+	}
+	return best
+}
+
+func (g *Game) familiarShoot(f *Familiar, dir Vec2) {
+	g.Bullets = append(g.Bullets, Bullet{
+		Pos:    f.Pos,
+		Prev:   f.Pos,
+		Vel:    Vec2{X: dir.X * familiarShotSpeed, Y: dir.Y * familiarShotSpeed},
+		Active: true,
+		Scale:  1,
+		Damage: familiarDamage,
+	})
+}
+
+// updateHalo eats enemy shots that touch the orbital and chips at enemies.
+func (g *Game) updateHalo(f *Familiar) {
+	for i := range g.EnemyShots {
+		s := &g.EnemyShots[i]
+		if s.Active && Distance(s.Pos, f.Pos) <= FamiliarRadius+g.Tune.EnemyShotRadius {
+			s.Active = false
+			g.spawnHitSparks(s.Pos)
+		}
+	}
+	if f.Cooldown > 0 {
+		return
+	}
+	for i := range g.Enemies {
+		e := &g.Enemies[i]
+		if e.Alive && Distance(e.Pos, f.Pos) <= FamiliarRadius+g.enemyRadiusOf(*e) {
+			g.hitEnemy(e, orbitDamage)
+			f.Cooldown = orbitHitDelay
+			return
+		}
+	}
+}
+
+// fetchPickup sends the magnet to the nearest pickup and collects it. It
+// reports whether the magnet had somewhere to go.
+func (g *Game) fetchPickup(f *Familiar) bool {
+	var target *Pickup
+	best := math.Inf(1)
+	for i := range g.Pickups {
+		p := &g.Pickups[i]
+		if p.Active && p.Kind != PickupTrinket && Distance(f.Pos, p.Pos) < best {
+			target, best = p, Distance(f.Pos, p.Pos)
+		}
+	}
+	if target == nil {
+		return false
+	}
+	moveToward(&f.Pos, target.Pos, 0)
+	if Distance(f.Pos, target.Pos) <= FamiliarRadius+g.Tune.PickupRadius {
+		target.Active = false
+		g.collectPickup(*target)
+	}
+	return true
+}
+
+func (g *Game) familiarList() []string {
+	names := make([]string, 0, len(g.Familiars))
+	for _, f := range g.Familiars {
+		names = append(names, familiarNames[f.Kind])
+	}
+	return names
+}
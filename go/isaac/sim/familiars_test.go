@@ -0,0 +1,21 @@
+package sim
+
+import "testing"
+
+// TestBuddyFiresWithFireHeld checks that Buddy follows the player's shots
+// when they fire with the fire key alone, in the last direction fired.
+func TestBuddyFiresWithFireHeld(t *testing.T) {
+	inTempDir(t)
+	g := NewGame()
+	g.StartRunWithSeed(6)
+	g.addFamiliar(FamiliarBuddy)
+	g.lastAimDir = Vec2{Y: -1}
+	g.input = TickInput{FireHeld: true}
+	g.updateFamiliars()
+	if g.Familiars[0].Cooldown != buddyShotDelay || len(g.Bullets) != 1 {
+		t.Fatalf("Buddy did not fire: cooldown %d, %d bullets", g.Familiars[0].Cooldown, len(g.Bullets))
+	}
+	if v := g.Bullets[0].Vel; v.X != 0 || v.Y >= 0 {
+		t.Errorf("Buddy fired %v, want up", v)
+	}
+}
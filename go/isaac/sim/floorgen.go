@@ -0,0 +1,321 @@
+package sim
+
+import (
+	"math/rand"
+	"os"
+	"sort"
+)
+
+const floorPlanAttempts = 40
+
+var LayoutDirs = [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+
+// layoutRNG seeds a floor's generation from the run seed and floor number
+// alone. Combat, drops and AI draw on the run RNG, so without it only floor 1
+// would come out the same for a seed.
+func layoutRNG(seed int64, floor int) *rand.Rand {
+	return rand.New(rand.NewSource(seed ^ int64(floor)*0x5DEECE66D))
+}
+
+// FloorGenerator produces the set of grid cells of a floor. Doors are implied
+// by adjacency, so a cell with a single neighbour is a dead end.
+type FloorGenerator interface {
+	Name() string
+	Cells(rng *rand.Rand, target int) [][2]int
+}
+
+type floorPlan struct {
+	Cells    [][2]int
+	Start    [2]int
+	Boss     [2]int
+	Shop     [2]int
+	Treasure [2]int
+}
+
+var floorGenerators = []FloorGenerator{
+	walkGenerator{},
+	isaacGenerator{},
+	treeGenerator{},
+	loopGenerator{},
+}
+
+func floorGeneratorByName(name string) (FloorGenerator, bool) {
+	for _, gen := range floorGenerators {
+		if gen.Name() == name {
+			return gen, true
+		}
+	}
+	return nil, false
+}
+
+// pickFloorGenerator selects the generator for the current floor. ISAAC_FLOORGEN
+// forces one by name, otherwise the first floor is always Isaac-style and later
+// floors roll among all generators.
+func (g *Game) pickFloorGenerator() FloorGenerator {
+	if gen, ok := floorGeneratorByName(os.Getenv("ISAAC_FLOORGEN")); ok {
+		return gen
+	}
+	if g.Floor <= 1 {
+		return isaacGenerator{}
+	}
+	return floorGenerators[g.rng.Intn(len(floorGenerators))]
+}
+
+// planFloor retries the generator until the special rooms satisfy the floor
+// constraints, then falls back to the relaxed placement of the first layout
+// and logs the floor in the run telemetry.
+func (g *Game) planFloor(gen FloorGenerator, target int) floorPlan {
+	var first [][2]int
+	for i := 0; i < floorPlanAttempts; i++ {
+		cells := gen.Cells(g.rng, target)
+		if first == nil {
+			first = cells
+		}
+		if plan, ok := assignSpecialRooms(cells, g.rng); ok {
+			return plan
+		}
+	}
+	g.relaxedFloors = append(g.relaxedFloors, g.Floor)
+	return relaxedFloorPlan(first, g.rng)
+}
+
+// assignSpecialRooms places boss and treasure rooms on dead ends (boss on the
+// farthest one) and the shop on a room not adjacent to the boss.
+func assignSpecialRooms(cells [][2]int, rng *rand.Rand) (floorPlan, bool) {
+	start := [2]int{0, 0}
+	plan := floorPlan{Cells: cells, Start: start}
+	occupied := cellSet(cells)
+	dist := cellDistances(occupied, start)
+
+	deadEnds := make([][2]int, 0, len(cells))
+	for _, c := range cells {
+		if c != start && neighbourCount(occupied, c) == 1 {
+			deadEnds = append(deadEnds, c)
+		}
+	}
+	if len(deadEnds) < 2 {
+		return plan, false
+	}
+	sort.SliceStable(deadEnds, func(i, j int) bool { return dist[deadEnds[i]] > dist[deadEnds[j]] })
+	plan.Boss = deadEnds[0]
+
+	treasure := deadEnds[1:]
+	plan.Treasure = treasure[rng.Intn(len(treasure))]
+
+	shopCandidates := make([][2]int, 0, len(cells))
+	for _, c := range cells {
+		if c == start || c == plan.Boss || c == plan.Treasure || cellsAdjacent(c, plan.Boss) {
+			continue
+		}
+		shopCandidates = append(shopCandidates, c)
+	}
+	if len(shopCandidates) == 0 {
+		return plan, false
+	}
+	plan.Shop = shopCandidates[rng.Intn(len(shopCandidates))]
+	return plan, true
+}
+
+// relaxedFloorPlan is the original placement: boss on the farthest cell by
+// grid distance and shop/treasure anywhere else. A layout with no room to
+// spare keeps shop and treasure on the start cell, which means none.
+func relaxedFloorPlan(cells [][2]int, rng *rand.Rand) floorPlan {
+	start := [2]int{0, 0}
+	plan := floorPlan{Cells: cells, Start: start, Boss: start, Shop: start, Treasure: start}
+	bestDist := -1
+	for _, c := range cells {
+		d := absInt(c[0]-start[0]) + absInt(c[1]-start[1])
+		if d > bestDist {
+			bestDist = d
+			plan.Boss = c
+		}
+	}
+	rest := make([][2]int, 0, len(cells))
+	for _, c := range cells {
+		if c != start && c != plan.Boss {
+			rest = append(rest, c)
+		}
+	}
+	if len(rest) == 0 {
+		return plan
+	}
+	plan.Shop = rest[rng.Intn(len(rest))]
+	plan.Treasure = plan.Shop
+	if len(rest) > 1 {
+		for plan.Treasure == plan.Shop {
+			plan.Treasure = rest[rng.Intn(len(rest))]
+		}
+	}
+	return plan
+}
+
+// walkGenerator is the original bounded random walk.
+type walkGenerator struct{}
+
+func (walkGenerator) Name() string { return "walk" }
+
+func (walkGenerator) Cells(rng *rand.Rand, target int) [][2]int {
+	seen := map[[2]int]bool{{0, 0}: true}
+	cells := [][2]int{{0, 0}}
+	current := [2]int{0, 0}
+
+	for len(cells) < target {
+		d := LayoutDirs[rng.Intn(len(LayoutDirs))]
+		next := [2]int{current[0] + d[0], current[1] + d[1]}
+		if !inLayoutBounds(next) {
+			current = cells[rng.Intn(len(cells))]
+			continue
+		}
+		if !seen[next] {
+			seen[next] = true
+			cells = append(cells, next)
+		}
+		if rng.Float64() < 0.55 {
+			current = next
+		} else {
+			current = cells[rng.Intn(len(cells))]
+		}
+	}
+	return cells
+}
+
+// isaacGenerator expands breadth-first from the start and refuses cells that
+// would touch more than one existing room, which yields corridors and many
+// dead ends.
+type isaacGenerator struct{}
+
+func (isaacGenerator) Name() string { return "isaac" }
+
+func (isaacGenerator) Cells(rng *rand.Rand, target int) [][2]int {
+	occupied := map[[2]int]bool{{0, 0}: true}
+	cells := [][2]int{{0, 0}}
+	queue := [][2]int{{0, 0}}
+
+	for reseeds := 0; len(queue) > 0 && len(cells) < target; {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, d := range LayoutDirs {
+			if len(cells) >= target {
+				break
+			}
+			next := [2]int{cur[0] + d[0], cur[1] + d[1]}
+			if occupied[next] || !inLayoutBounds(next) || neighbourCount(occupied, next) > 1 {
+				continue
+			}
+			if rng.Float64() < 0.5 && cur != [2]int{0, 0} {
+				continue
+			}
+			occupied[next] = true
+			cells = append(cells, next)
+			queue = append(queue, next)
+		}
+		if len(queue) == 0 && len(cells) < target && reseeds < target*4 {
+			// Seed another pass from a random room so small floors still fill up.
+			reseeds++
+			queue = append(queue, cells[rng.Intn(len(cells))])
+		}
+	}
+	return cells
+}
+
+// treeGenerator grows branches from random rooms, only ever attaching a cell
+// to a single parent, so the layout is a tree with no loops.
+type treeGenerator struct{}
+
+func (treeGenerator) Name() string { return "tree" }
+
+func (treeGenerator) Cells(rng *rand.Rand, target int) [][2]int {
+	occupied := map[[2]int]bool{{0, 0}: true}
+	cells := [][2]int{{0, 0}}
+	for tries := 0; len(cells) < target && tries < target*200; tries++ {
+		parent := cells[rng.Intn(len(cells))]
+		d := LayoutDirs[rng.Intn(len(LayoutDirs))]
+		next := [2]int{parent[0] + d[0], parent[1] + d[1]}
+		if occupied[next] || !inLayoutBounds(next) || neighbourCount(occupied, next) != 1 {
+			continue
+		}
+		occupied[next] = true
+		cells = append(cells, next)
+	}
+	return cells
+}
+
+// loopGenerator builds a tree and then fills a few cells that touch two
+// branches at once, closing loops through the floor.
+type loopGenerator struct{}
+
+func (loopGenerator) Name() string { return "loop" }
+
+func (loopGenerator) Cells(rng *rand.Rand, target int) [][2]int {
+	loops := 2
+	cells := treeGenerator{}.Cells(rng, maxInt(4, target-loops))
+	occupied := cellSet(cells)
+
+	candidates := make([][2]int, 0)
+	for _, c := range cells {
+		for _, d := range LayoutDirs {
+			next := [2]int{c[0] + d[0], c[1] + d[1]}
+			if !occupied[next] && inLayoutBounds(next) && neighbourCount(occupied, next) >= 2 {
+				candidates = append(candidates, next)
+			}
+		}
+	}
+	rng.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	for _, c := range candidates {
+		if loops == 0 {
+			break
+		}
+		if occupied[c] {
+			continue
+		}
+		occupied[c] = true
+		cells = append(cells, c)
+		loops--
+	}
+	return cells
+}
+
+func inLayoutBounds(c [2]int) bool {
+	return absInt(c[0]) <= layoutBound && absInt(c[1]) <= layoutBound
+}
+
+func cellSet(cells [][2]int) map[[2]int]bool {
+	set := make(map[[2]int]bool, len(cells))
+	for _, c := range cells {
+		set[c] = true
+	}
+	return set
+}
+
+func neighbourCount(occupied map[[2]int]bool, c [2]int) int {
+	n := 0
+	for _, d := range LayoutDirs {
+		if occupied[[2]int{c[0] + d[0], c[1] + d[1]}] {
+			n++
+		}
+	}
+	return n
+}
+
+func cellsAdjacent(a, b [2]int) bool {
+	return absInt(a[0]-b[0])+absInt(a[1]-b[1]) == 1
+}
+
+// cellDistances returns the door-walking distance of every reachable cell.
+func cellDistances(occupied map[[2]int]bool, from [2]int) map[[2]int]int {
+	dist := map[[2]int]int{from: 0}
+	queue := [][2]int{from}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, d := range LayoutDirs {
+			next := [2]int{cur[0] + d[0], cur[1] + d[1]}
+			if _, seen := dist[next]; seen || !occupied[next] {
+				continue
+			}
+			dist[next] = dist[cur] + 1
+			queue = append(queue, next)
+		}
+	}
+	return dist
+}
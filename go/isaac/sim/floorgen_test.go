@@ -0,0 +1,48 @@
+package sim
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestFloorLayoutFromSeed checks that floor 2 comes out the same for a seed
+// however much the run RNG was used on floor 1.
+func TestFloorLayoutFromSeed(t *testing.T) {
+	inTempDir(t)
+	layout := func(g *Game) map[[2]int]RoomType {
+		cells := map[[2]int]RoomType{}
+		for _, r := range g.Rooms {
+			cells[[2]int{r.GridX, r.GridY}] = r.Type
+		}
+		return cells
+	}
+	a := NewGame()
+	a.StartRunWithSeed(11)
+	a.startNextFloor()
+
+	b := NewGame()
+	b.StartRunWithSeed(11)
+	for i := 0; i < 500; i++ {
+		b.rng.Float64()
+	}
+	b.startNextFloor()
+
+	if a.Floor != 2 || b.Floor != 2 {
+		t.Fatalf("floors %d and %d, want 2", a.Floor, b.Floor)
+	}
+	if la, lb := layout(a), layout(b); !reflect.DeepEqual(la, lb) {
+		t.Errorf("floor 2 differs:\n%v\n%v", la, lb)
+	}
+}
+
+// TestRelaxedPlanTinyLayout checks that the fallback copes with layouts too
+// small to hold a shop or treasure room.
+func TestRelaxedPlanTinyLayout(t *testing.T) {
+	rng := layoutRNG(1, 1)
+	for _, cells := range [][][2]int{{{0, 0}}, {{0, 0}, {1, 0}}} {
+		plan := relaxedFloorPlan(cells, rng)
+		if plan.Shop != plan.Start || plan.Treasure != plan.Start {
+			t.Errorf("%d cells: shop %v and treasure %v placed off the start", len(cells), plan.Shop, plan.Treasure)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package sim
+
+import "math"
+
+// TickInput is everything one simulation tick reads from the player. Held
+// values are overwritten on every poll; presses stay latched until a tick
+// consumes them, so a press is seen exactly once whatever the TPS.
+type TickInput struct {
+	Move      Vec2 `json:"move"`
+	Aim       Vec2 `json:"aim"`
+	FireHeld  bool `json:"fire,omitempty"`
+	AimAssist bool `json:"assist,omitempty"` // Aim came from a gamepad stick
+
+	Dash    bool `json:"dash,omitempty"`
+	Bomb    bool `json:"bomb,omitempty"`
+	Active  bool `json:"active,omitempty"`
+	Chest   bool `json:"chest,omitempty"`
+	Buy     bool `json:"buy,omitempty"`
+	Reroll  bool `json:"reroll,omitempty"`
+	Descend bool `json:"descend,omitempty"`
+	Trinket bool `json:"trinket,omitempty"`
+}
+
+func (in *TickInput) Merge(next TickInput) {
+	in.Move = next.Move
+	in.Aim = next.Aim
+	in.FireHeld = next.FireHeld
+	in.AimAssist = next.AimAssist
+	in.Dash = in.Dash || next.Dash
+	in.Bomb = in.Bomb || next.Bomb
+	in.Active = in.Active || next.Active
+	in.Chest = in.Chest || next.Chest
+	in.Buy = in.Buy || next.Buy
+	in.Reroll = in.Reroll || next.Reroll
+	in.Descend = in.Descend || next.Descend
+	in.Trinket = in.Trinket || next.Trinket
+}
+
+// Held drops the latched presses once a tick has seen them.
+func (in TickInput) Held() TickInput {
+	return TickInput{Move: in.Move, Aim: in.Aim, FireHeld: in.FireHeld, AimAssist: in.AimAssist}
+}
+
+// assistAim bends a stick aim towards the enemy closest to its line, if one
+// lies inside the assist cone.
+func (g *Game) assistAim(dir Vec2) Vec2 {
+	cone := g.Settings.AimAssist * math.Pi / 180
+	if cone <= 0 {
+		return dir
+	}
+	aim := math.Atan2(dir.Y, dir.X)
+	best := cone
+	out := dir
+	for _, e := range g.Enemies {
+		if !e.Alive {
+			continue
+		}
+		to := math.Atan2(e.Pos.Y-g.PlayerPos.Y, e.Pos.X-g.PlayerPos.X)
+		if off := math.Abs(math.Remainder(to-aim, 2*math.Pi)); off < best {
+			best = off
+			out = Vec2{X: math.Cos(to), Y: math.Sin(to)}
+		}
+	}
+	return out
+}
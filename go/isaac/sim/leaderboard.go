@@ -0,0 +1,122 @@
+package sim
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const LeaderboardSize = 10
+
+type LeaderboardEntry struct {
+	Seed      int64    `json:"seed"`
+	Score     int      `json:"score"`
+	Rank      string   `json:"rank"`
+	Floor     int      `json:"floor"`
+	Character string   `json:"character"`
+	Items     []string `json:"items"`
+	Date      string   `json:"date"`
+	Result    string   `json:"result"`
+	GameSpeed float64  `json:"game_speed,omitempty"`
+}
+
+func (g *Game) leaderboardPath() string { return filepath.Join(".", "leaderboard.json") }
+
+func (g *Game) LeaderboardExportPath() string {
+	return filepath.Join(".", "leaderboard_export.csv")
+}
+
+func (g *Game) loadLeaderboard() {
+	g.Leaderboard = map[string][]LeaderboardEntry{}
+	data, err := os.ReadFile(g.leaderboardPath())
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, &g.Leaderboard)
+}
+
+func (g *Game) saveLeaderboard() {
+	if g.Playback {
+		return
+	}
+	data, err := json.MarshalIndent(g.Leaderboard, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(g.leaderboardPath(), data, 0644)
+}
+
+// recordLeaderboard stores the finished run if it makes the top of its mode.
+func (g *Game) recordLeaderboard(result string) {
+	if g.Score <= 0 || g.unranked() {
+		return
+	}
+	if g.Leaderboard == nil {
+		g.Leaderboard = map[string][]LeaderboardEntry{}
+	}
+	items := make([]string, 0, len(g.CollectedItems))
+	for _, it := range g.CollectedItems {
+		items = append(items, itemNames[it])
+	}
+	entry := LeaderboardEntry{
+		Seed:      g.RunSeed,
+		Score:     g.Score,
+		Rank:      g.RunRank(),
+		Floor:     g.Floor,
+		Character: g.CharacterID,
+		Items:     items,
+		Date:      time.Now().Format("2006-01-02 15:04"),
+		Result:    result,
+		GameSpeed: g.runSpeed,
+	}
+	mode := g.Mode.String()
+	entries := append(g.Leaderboard[mode], entry)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Score > entries[j].Score })
+	if len(entries) > LeaderboardSize {
+		entries = entries[:LeaderboardSize]
+	}
+	g.Leaderboard[mode] = entries
+	g.saveLeaderboard()
+}
+
+func (g *Game) ExportLeaderboard() error {
+	f, err := os.Create(g.LeaderboardExportPath())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	_ = w.Write([]string{"mode", "position", "score", "rank", "floor", "character", "seed", "result", "date", "items"})
+	for _, m := range RunModes {
+		for i, e := range g.Leaderboard[m.String()] {
+			_ = w.Write([]string{
+				m.String(), strconv.Itoa(i + 1), strconv.Itoa(e.Score), e.Rank, strconv.Itoa(e.Floor),
+				e.Character, strconv.FormatInt(e.Seed, 10), e.Result, e.Date, strings.Join(e.Items, ";"),
+			})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// StartRunWithSeed begins a fresh run from a known seed, so the floor layouts
+// match the stored run. An abandoned run goes to telemetry only; the board
+// keeps runs that ended.
+func (g *Game) StartRunWithSeed(seed int64) {
+	if g.RunTicks > 0 {
+		g.saveRunTelemetry("new_run")
+		g.finishSplits()
+	}
+	g.RunSeed = seed
+	g.rng = rand.New(rand.NewSource(g.RunSeed))
+	g.Floor = 1
+	g.BestScore = g.ModeBest[g.Mode.String()]
+	g.beginReplay()
+	g.ResetRun()
+}
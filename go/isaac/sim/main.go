@@ -0,0 +1,1935 @@
+// Package sim holds the game rules: floors, rooms, combat, items and the
+// per-tick step. It draws nothing and polls no input, so any frontend can
+// drive it with TickInput and render the exported state.
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"isaac/i18n"
+)
+
+const (
+	ScreenW = 960
+	ScreenH = 540
+
+	RoomMargin = 48
+	DoorHalf   = 34
+
+	PlayerRadius = 14
+	playerSpeed  = 3.2
+	playerMaxHP  = 6
+
+	fireCooldownTicks = 8
+	bulletDamage      = 1
+	bombStartCount    = 3
+
+	roomSwapCooldown = 14
+	ItemRadius       = 10
+	itemTextDuration = 140
+
+	ExplosionTicks     = 14
+	shopInteractRadius = 26
+	StreakTimeoutTicks = 190
+	LowHPThreshold     = 2
+	spikeDamageTick    = 24
+	TransitionTicksMax = 18
+
+	proceduralCombatRooms = 10
+	layoutBound           = 4
+)
+
+type Vec2 struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type Bullet struct {
+	Pos    Vec2
+	Prev   Vec2
+	Vel    Vec2
+	Active bool
+	Pierce int
+
+	Flags     WeaponFlag
+	Scale     float64
+	Age       int
+	Returning bool
+	Damage    int // fixed damage for familiar tears; 0 rolls the player's
+}
+
+type EnemyShot struct {
+	Pos      Vec2
+	Prev     Vec2
+	Vel      Vec2
+	Active   bool
+	FromBoss bool
+}
+
+type Bomb struct {
+	Pos    Vec2
+	Timer  int
+	Active bool
+}
+
+type Explosion struct {
+	Pos    Vec2
+	Timer  int
+	Radius float64
+}
+
+type EnemyType int
+
+const (
+	EnemyChaser EnemyType = iota
+	EnemyWander
+	EnemyShooter
+	EnemyDasher
+	EnemyBoss
+	EnemySpawner
+)
+
+type Enemy struct {
+	Pos           Vec2
+	Prev          Vec2
+	Vel           Vec2
+	HP            int
+	Kind          EnemyType
+	Alive         bool
+	WanderTimer   int
+	ShootCooldown int
+	ShootWindup   int
+	BossRingCD    int
+	MaxHP         int
+	SpawnCD       int
+	Minion        bool
+	Champion      ChampionType
+	Mod           string     // mod enemy id, "" for built-in kinds
+	Tint          color.RGBA // mod enemy colour
+}
+
+type ItemType int
+
+const (
+	ItemDamage ItemType = iota
+	ItemFireRate
+	ItemSpeed
+	ItemHeal
+	ItemCrit
+	ItemPierce
+	ItemMultiShot
+	ItemBombMaster
+	ItemLuck
+	ItemShield
+	ItemCompass
+	ItemLaser
+	ItemChargeShot
+	ItemHoming
+	ItemBoomerang
+	ItemExplosive
+	ItemBuddy
+	ItemSentry
+	ItemHalo
+	ItemMagnet
+
+	itemTypeCount
+)
+
+var itemNames = map[ItemType]string{
+	ItemDamage:     "Blood Drop",
+	ItemFireRate:   "Torn Page",
+	ItemSpeed:      "Running Shoe",
+	ItemHeal:       "Heart Patch",
+	ItemCrit:       "Sharp Eye",
+	ItemPierce:     "Needle Tear",
+	ItemMultiShot:  "Twin Eye",
+	ItemBombMaster: "Bomber Kit",
+	ItemLuck:       "Lucky Charm",
+	ItemShield:     "Halo Shield",
+	ItemCompass:    "Compass",
+	ItemLaser:      "Blood Laser",
+	ItemChargeShot: "Heavy Heart",
+	ItemHoming:     "Bent Spoon",
+	ItemBoomerang:  "Return Tear",
+	ItemExplosive:  "Sulfur Tear",
+	ItemBuddy:      "Little Brother",
+	ItemSentry:     "Watchful Eye",
+	ItemHalo:       "Orbiting Halo",
+	ItemMagnet:     "Coin Magnet",
+}
+
+type Item struct {
+	Pos   Vec2
+	Kind  ItemType
+	Taken bool
+	Mod   string // mod item id; Kind is ignored when set
+}
+
+type PickupType int
+
+const (
+	PickupHeart PickupType = iota
+	PickupBomb
+	PickupCoin
+	PickupKey
+	PickupSoulHeart
+	PickupHeartContainer
+	PickupNickel
+	PickupDime
+	PickupGoldenKey
+	PickupGoldenBomb
+	PickupBattery
+	PickupTrinket
+)
+
+type Pickup struct {
+	Pos     Vec2
+	Kind    PickupType
+	Trinket TrinketType
+	Active  bool
+}
+
+type RoomType int
+
+const (
+	RoomStart RoomType = iota
+	RoomCombat
+	RoomShop
+	RoomBoss
+	RoomTreasure
+	RoomSecret
+)
+
+type OfferType int
+
+const (
+	OfferHeart OfferType = iota
+	OfferBombPack
+	OfferKey
+	OfferSoulHeart
+	OfferItem
+)
+
+// ShopOffer is one pedestal in a shop. Price is the base price before sales,
+// character discounts and the shopkeeper markup; see offerPrice.
+type ShopOffer struct {
+	Pos       Vec2
+	Kind      OfferType
+	Item      ItemType
+	Price     int
+	Sale      bool
+	Free      bool
+	Purchased bool
+}
+
+type Chest struct {
+	Pos    Vec2
+	Opened bool
+}
+
+type Hazard struct {
+	Pos Vec2    `json:"pos"`
+	R   float64 `json:"r"`
+}
+
+type Room struct {
+	ID       int
+	GridX    int
+	GridY    int
+	Type     RoomType
+	Enemies  []Enemy
+	Reward   Item
+	Pickups  []Pickup
+	Offers   []ShopOffer
+	Chests   []Chest
+	Hazards  []Hazard
+	Decals   []Decal
+	Template string
+	Keeper   bool
+	// Cleared is set once the room has been clear, so enemies spawned into
+	// it later do not pay the room-clear rewards again.
+	Cleared bool
+
+	Waves     [][]Enemy
+	WaveTotal int
+}
+
+// RoomTemplate is the layout of a combat room. The built-in set can be
+// replaced by room_templates.json, which the editor (-editor) writes.
+type RoomTemplate struct {
+	Name       string   `json:"name"`
+	Hazards    []Hazard `json:"hazards"`
+	ChestPos   []Vec2   `json:"chests"`
+	EnemySlots []Vec2   `json:"enemy_slots"`
+}
+
+type Game struct {
+	Scene       Scene
+	CharacterID string
+	Mode        RunMode
+
+	Settings Settings
+	catalog  *i18n.Catalog
+	Tune     Tuning
+	input    TickInput
+	FX       ParticlePool
+
+	RushComplete  bool
+	ModeBest      map[string]int
+	EndlessFloors []int
+
+	Leaderboard    map[string][]LeaderboardEntry
+	CollectedItems []ItemType
+
+	PlayerPos      Vec2
+	PlayerPrevPos  Vec2
+	PlayerHP       int
+	MaxHP          int
+	PlayerInvTicks int
+	fireCooldown   int
+	swapCooldown   int
+	ItemTextTicks  int
+	LastItemText   string
+	bombPlaceCD    int
+	spikeTick      int
+
+	MoveSpeed        float64
+	ShotCooldownBase int
+	ShotDamage       int
+	damageMult       float64
+	CritChance       float64
+	critMult         float64
+	luck             float64
+	pierceCount      int
+	multiShot        bool
+	Weapon           WeaponFlag
+	ChargeTicks      int
+	Familiars        []Familiar
+	ShieldCharges    int
+	MaxShieldCharges int
+	bombRadiusMult   float64
+	bombDamageBonus  int
+	lastAimDir       Vec2
+	ActiveItem       ActiveType
+	ActiveCharge     int
+
+	dashDir      Vec2
+	DashTicks    int
+	dashCooldown int
+	lastMoveDir  Vec2
+
+	Bombs      int
+	Coins      int
+	Keys       int
+	SoulHP     int
+	GoldenKey  bool
+	GoldenBomb bool
+
+	Bullets    []Bullet
+	EnemyShots []EnemyShot
+	Enemies    []Enemy
+	BombList   []Bomb
+	Explosions []Explosion
+	Beams      []Beam
+	Pickups    []Pickup
+	Offers     []ShopOffer
+	Chests     []Chest
+	Hazards    []Hazard
+
+	rng            *rand.Rand
+	RunSeed        int64
+	RunTicks       int
+	RoomClear      bool
+	Rooms          map[int]*Room
+	GridToRoomID   map[[2]int]int
+	CurrentRoomID  int
+	VisitedRooms   map[int]bool
+	StatusText     string
+	StatusTextTick int
+	Paused         bool
+	ShowMiniMap    bool
+	ShowFullMap    bool
+	hasCompass     bool
+	TransitionTick int
+	ShakeTick      int
+	ShakeMag       float64
+
+	bossRoomID     int
+	shopRoomID     int
+	treasureRoomID int
+	Doors          map[[4]int]*Door
+	bossDoorsOpen  bool
+	FloorGenName   string
+	Floor          int
+	floorsCleared  int
+
+	Score         int
+	BestScore     int
+	killCount     int
+	KillStreak    int
+	StreakTick    int
+	waveDelay     int
+	RunsCompleted int
+	Deaths        int
+	ShopRerolls   int
+	keepersKilled int
+	Cheated       bool
+	runSpeed      float64 // slowest game speed the run has been played at
+	godMode       bool
+	purchases     []PurchaseRecord
+	Templates     []RoomTemplate
+
+	mods          []*Mod
+	modItems      []ModItem
+	modEnemies    []ModEnemy
+	modDepth      int
+	modSpawns     []Enemy
+	modBullets    []Bullet
+	modShots      []EnemyShot
+	collectedMods []string
+
+	Splits      []SplitResult
+	SplitFile   SplitFile
+	speedrunSrv *speedrunServer
+
+	Curse         Curse
+	curseLog      []FloorCurse
+	relaxedFloors []int
+	Trinket       TrinketType
+	trinketDropCD int
+	trinketLog    []string
+
+	replay   Replay
+	Playback bool // a replay is driving the game; nothing is saved
+
+	runRoomsVisited int
+	runDamageTaken  int
+	runDamageDealt  int
+}
+
+type MetaSave struct {
+	BestScore     int            `json:"best_score"`
+	RunsCompleted int            `json:"runs_completed"`
+	Deaths        int            `json:"deaths"`
+	Character     string         `json:"character"`
+	Mode          string         `json:"mode"`
+	ModeBest      map[string]int `json:"mode_best"`
+	EndlessFloors []int          `json:"endless_floors"`
+}
+
+type RunTelemetry struct {
+	Timestamp       string `json:"timestamp"`
+	Seed            int64  `json:"seed"`
+	Floor           int    `json:"floor"`
+	Generator       string `json:"generator"`
+	Character       string `json:"character"`
+	Mode            string `json:"mode"`
+	Score           int    `json:"score"`
+	RoomsVisited    int    `json:"rooms_visited"`
+	EnemiesDefeated int    `json:"enemies_defeated"`
+	DamageTaken     int    `json:"damage_taken"`
+	DamageDealt     int    `json:"damage_dealt"`
+	RunSeconds      int    `json:"run_seconds"`
+	Rank            string `json:"rank"`
+	Result          string `json:"result"`
+
+	ShopkeepersKilled int              `json:"shopkeepers_killed"`
+	Purchases         []PurchaseRecord `json:"purchases,omitempty"`
+	Cheated           bool             `json:"cheated,omitempty"`
+	GameSpeed         float64          `json:"game_speed"`
+	Familiars         []string         `json:"familiars,omitempty"`
+	Mods              []string         `json:"mods,omitempty"`
+	ModItems          []string         `json:"mod_items,omitempty"`
+	Curses            []FloorCurse     `json:"curses,omitempty"`
+	Trinkets          []string         `json:"trinkets,omitempty"`
+	LayoutRelaxed     []int            `json:"layout_relaxed,omitempty"`
+}
+
+func NewGame() *Game {
+	settings := LoadSettings()
+	g := &Game{Settings: settings, catalog: i18n.Load(settings.Lang)}
+	tune, _, tuneErr := LoadTuning()
+	g.Tune = tune
+	g.Templates = LoadRoomTemplates()
+	g.loadMods()
+	g.startSpeedrunServer()
+	g.loadMeta()
+	g.loadLeaderboard()
+	g.StartNewRun()
+	if tuneErr != nil {
+		g.StatusText = g.Tr("debug.tuning_error", tuneErr)
+		g.StatusTextTick = 300
+	}
+	return g
+}
+
+func (g *Game) StartNewRun() {
+	g.StartRunWithSeed(time.Now().UnixNano())
+}
+
+func (g *Game) ResetRun() {
+	g.recordReset()
+	if g.rng == nil {
+		g.rng = rand.New(rand.NewSource(g.RunSeed))
+	}
+
+	g.PlayerPos = Vec2{X: ScreenW / 2, Y: ScreenH / 2}
+	g.PlayerPrevPos = g.PlayerPos
+	g.FX.reset(g.RunSeed)
+	g.PlayerInvTicks = 0
+	g.fireCooldown = 0
+	g.swapCooldown = 0
+	g.ItemTextTicks = 0
+	g.LastItemText = ""
+	g.bombPlaceCD = 0
+	g.spikeTick = 0
+	g.MoveSpeed = playerSpeed
+	g.ShotCooldownBase = fireCooldownTicks
+	g.ShotDamage = bulletDamage
+	g.CritChance = 0.08
+	g.critMult = 1.8
+	g.luck = 0
+	g.pierceCount = 0
+	g.multiShot = false
+	g.ShieldCharges = 0
+	g.MaxShieldCharges = 0
+	g.bombRadiusMult = 1.0
+	g.bombDamageBonus = 0
+	g.damageMult = 1.0
+	g.lastAimDir = Vec2{X: 1, Y: 0}
+	g.dashDir = Vec2{}
+	g.DashTicks = 0
+	g.dashCooldown = 0
+	g.lastMoveDir = Vec2{X: 1, Y: 0}
+	g.Bombs = bombStartCount
+	g.Coins = 0
+	g.Keys = 0
+	g.Weapon = 0
+	g.Familiars = g.Familiars[:0]
+	g.ChargeTicks = 0
+	g.Beams = g.Beams[:0]
+	g.Bullets = g.Bullets[:0]
+	g.EnemyShots = g.EnemyShots[:0]
+	g.BombList = g.BombList[:0]
+	g.Explosions = g.Explosions[:0]
+	g.Pickups = g.Pickups[:0]
+	g.Offers = g.Offers[:0]
+	g.Chests = g.Chests[:0]
+	g.Hazards = g.Hazards[:0]
+	g.StatusText = ""
+	g.StatusTextTick = 0
+	g.Paused = false
+	g.ShowMiniMap = true
+	g.hasCompass = false
+	g.SoulHP = 0
+	g.GoldenKey = false
+	g.GoldenBomb = false
+	g.TransitionTick = 0
+	g.ShakeTick = 0
+	g.ShakeMag = 0
+	g.Score = 0
+	g.killCount = 0
+	g.KillStreak = 0
+	g.StreakTick = 0
+	g.RunTicks = 0
+	g.ShopRerolls = 0
+	g.keepersKilled = 0
+	g.Cheated = false
+	g.runSpeed = MaxGameSpeed
+	g.godMode = false
+	g.purchases = g.purchases[:0]
+	g.runRoomsVisited = 1
+	g.runDamageTaken = 0
+	g.runDamageDealt = 0
+	g.floorsCleared = 0
+	g.RushComplete = false
+	g.CollectedItems = g.CollectedItems[:0]
+	g.collectedMods = g.collectedMods[:0]
+	g.Splits = g.Splits[:0]
+	g.loadSplitFile()
+	g.modSpawns = g.modSpawns[:0]
+	g.modBullets = g.modBullets[:0]
+	g.modShots = g.modShots[:0]
+	g.Curse = CurseNone
+	g.curseLog = g.curseLog[:0]
+	g.relaxedFloors = g.relaxedFloors[:0]
+	g.Trinket = TrinketNone
+	g.trinketDropCD = 0
+	g.trinketLog = g.trinketLog[:0]
+	g.applyCharacter(g.Character())
+
+	g.initRoomsProcedural()
+	g.VisitedRooms = map[int]bool{g.CurrentRoomID: true}
+	g.LoadCurrentRoom()
+	g.updateRoomClear()
+}
+
+func (g *Game) initRoomsProcedural() {
+	run := g.rng
+	g.rng = layoutRNG(g.RunSeed, g.Floor)
+	defer func() { g.rng = run }()
+	if g.Mode == ModeBossRush {
+		g.initBossRushRooms()
+		return
+	}
+	targetRooms := proceduralCombatRooms + 3 + minInt(5, g.Floor-1)
+	gen := g.pickFloorGenerator()
+	g.FloorGenName = gen.Name()
+	plan := g.planFloor(gen, targetRooms)
+	cells := plan.Cells
+	startCell := plan.Start
+	secretCell, hasSecret := pickSecretCell(plan, g.rng)
+	if hasSecret {
+		cells = append(cells, secretCell)
+	}
+
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i][0] == cells[j][0] {
+			return cells[i][1] < cells[j][1]
+		}
+		return cells[i][0] < cells[j][0]
+	})
+
+	g.Rooms = make(map[int]*Room, len(cells))
+	g.GridToRoomID = make(map[[2]int]int, len(cells))
+	idForCell := make(map[[2]int]int, len(cells))
+
+	nextID := 0
+	for _, c := range cells {
+		idForCell[c] = nextID
+		nextID++
+	}
+
+	for _, c := range cells {
+		id := idForCell[c]
+		r := &Room{ID: id, GridX: c[0], GridY: c[1], Type: RoomCombat}
+
+		switch {
+		case c == startCell:
+			r.Type = RoomStart
+			g.CurrentRoomID = id
+			g.populateStartRoom(r)
+		case c == plan.Boss:
+			r.Type = RoomBoss
+			g.bossRoomID = id
+			g.populateBossRoom(r)
+		case c == plan.Shop:
+			r.Type = RoomShop
+			g.shopRoomID = id
+			g.populateShopRoom(r)
+		case c == plan.Treasure:
+			r.Type = RoomTreasure
+			g.treasureRoomID = id
+			g.populateTreasureRoom(r)
+		case hasSecret && c == secretCell:
+			r.Type = RoomSecret
+			g.populateSecretRoom(r)
+		default:
+			depth := absInt(c[0]-startCell[0]) + absInt(c[1]-startCell[1])
+			g.PopulateCombatRoom(r, depth)
+		}
+
+		g.Rooms[id] = r
+		g.GridToRoomID[[2]int{c[0], c[1]}] = id
+	}
+	g.bossDoorsOpen = false
+	g.initDoors()
+}
+
+func (g *Game) populateStartRoom(r *Room) {
+	r.Reward = Item{Pos: Vec2{X: ScreenW / 2, Y: ScreenH / 2}, Kind: ItemHeal}
+	r.Chests = []Chest{{Pos: Vec2{X: 180, Y: 420}}}
+	// Keep hazards away from the spawn area (screen center).
+	r.Hazards = []Hazard{{Pos: Vec2{X: 700, Y: 340}, R: 17}}
+	r.Enemies = []Enemy{
+		{Pos: Vec2{X: 220, Y: 180}, HP: 3, Kind: EnemyChaser, Alive: true},
+	}
+}
+
+func (g *Game) PopulateCombatRoom(r *Room, depth int) {
+	templates := g.RoomTemplates()
+	tpl := templates[g.rng.Intn(len(templates))]
+	r.Template = tpl.Name
+	r.Hazards = append(r.Hazards[:0], tpl.Hazards...)
+	for _, c := range tpl.ChestPos {
+		r.Chests = append(r.Chests, Chest{Pos: c})
+	}
+
+	enemyCount := minInt(len(tpl.EnemySlots), 2+minInt(4, (depth+g.Floor)/2))
+	r.Enemies = make([]Enemy, 0, enemyCount+1)
+	for i := 0; i < enemyCount; i++ {
+		r.Enemies = append(r.Enemies, g.rollModEnemy(g.rollEnemy(tpl.EnemySlots[i], depth)))
+	}
+	if g.rng.Float64() < g.spawnerChance() {
+		r.Enemies = append(r.Enemies, g.newSpawner(tpl.EnemySlots[g.rng.Intn(len(tpl.EnemySlots))], depth))
+	}
+	r.Waves = g.rollWaves(tpl, depth)
+	r.WaveTotal = 1 + len(r.Waves)
+
+	if g.rng.Float64() < 0.20+0.05*float64(minInt(6, g.Floor)) {
+		r.Chests = append(r.Chests, Chest{Pos: Vec2{X: 120 + g.rng.Float64()*720, Y: 100 + g.rng.Float64()*320}})
+	}
+	r.Reward = g.rollReward()
+}
+
+func (g *Game) RoomTemplates() []RoomTemplate {
+	if len(g.Templates) == 0 {
+		g.Templates = LoadRoomTemplates()
+	}
+	return g.Templates
+}
+
+func builtinRoomTemplates() []RoomTemplate {
+	return []RoomTemplate{
+		{
+			Name:       "Arena",
+			Hazards:    []Hazard{{Pos: Vec2{X: 480, Y: 270}, R: 18}},
+			ChestPos:   []Vec2{{X: 140, Y: 420}},
+			EnemySlots: []Vec2{{X: 220, Y: 160}, {X: 730, Y: 160}, {X: 220, Y: 380}, {X: 730, Y: 380}, {X: 480, Y: 150}},
+		},
+		{
+			Name:       "Crossfire",
+			Hazards:    []Hazard{{Pos: Vec2{X: 350, Y: 220}, R: 14}, {Pos: Vec2{X: 610, Y: 320}, R: 14}},
+			ChestPos:   []Vec2{{X: 820, Y: 120}},
+			EnemySlots: []Vec2{{X: 180, Y: 270}, {X: 780, Y: 270}, {X: 480, Y: 140}, {X: 480, Y: 400}, {X: 680, Y: 180}},
+		},
+		{
+			Name:       "Gauntlet",
+			Hazards:    []Hazard{{Pos: Vec2{X: 300, Y: 180}, R: 16}, {Pos: Vec2{X: 480, Y: 270}, R: 16}, {Pos: Vec2{X: 660, Y: 360}, R: 16}},
+			ChestPos:   []Vec2{{X: 120, Y: 120}},
+			EnemySlots: []Vec2{{X: 200, Y: 120}, {X: 760, Y: 120}, {X: 200, Y: 420}, {X: 760, Y: 420}, {X: 480, Y: 270}},
+		},
+		{
+			Name:       "Corners",
+			Hazards:    []Hazard{{Pos: Vec2{X: 480, Y: 120}, R: 13}, {Pos: Vec2{X: 480, Y: 420}, R: 13}},
+			ChestPos:   []Vec2{{X: 820, Y: 420}},
+			EnemySlots: []Vec2{{X: 140, Y: 120}, {X: 820, Y: 120}, {X: 140, Y: 420}, {X: 820, Y: 420}, {X: 480, Y: 270}},
+		},
+		{
+			Name:       "Midlane",
+			Hazards:    []Hazard{{Pos: Vec2{X: 390, Y: 270}, R: 15}, {Pos: Vec2{X: 570, Y: 270}, R: 15}},
+			ChestPos:   []Vec2{{X: 160, Y: 100}},
+			EnemySlots: []Vec2{{X: 230, Y: 200}, {X: 730, Y: 200}, {X: 230, Y: 340}, {X: 730, Y: 340}, {X: 480, Y: 130}},
+		},
+		{
+			Name:       "Open",
+			Hazards:    nil,
+			ChestPos:   []Vec2{{X: 820, Y: 100}},
+			EnemySlots: []Vec2{{X: 240, Y: 150}, {X: 720, Y: 150}, {X: 240, Y: 390}, {X: 720, Y: 390}, {X: 480, Y: 270}},
+		},
+	}
+}
+
+func (g *Game) populateTreasureRoom(r *Room) {
+	r.Reward = g.rollReward()
+}
+
+func (g *Game) populateSecretRoom(r *Room) {
+	r.Reward = Item{Taken: true}
+	for i := 0; i < 3; i++ {
+		r.Pickups = append(r.Pickups, Pickup{Pos: Vec2{X: ScreenW/2 - 30 + float64(i)*30, Y: ScreenH / 2}, Kind: PickupCoin, Active: true})
+	}
+	r.Pickups = append(r.Pickups, Pickup{Pos: Vec2{X: ScreenW / 2, Y: ScreenH/2 + 40}, Kind: PickupBomb, Active: true})
+}
+
+func (g *Game) populateBossRoom(r *Room) {
+	r.Reward = Item{Taken: true}
+	r.Enemies = []Enemy{g.newBoss(Vec2{X: ScreenW / 2, Y: ScreenH / 2})}
+}
+
+func (g *Game) newBoss(pos Vec2) Enemy {
+	hp := int(float64(g.Tune.BossHP) * g.modeHPScale())
+	return Enemy{Pos: pos, HP: hp, MaxHP: hp, Kind: EnemyBoss, Alive: true, ShootCooldown: g.Tune.BossShotDelay, BossRingCD: g.Tune.BossRingDelayP3}
+}
+
+func (g *Game) LoadCurrentRoom() {
+	room := g.CurrentRoom()
+	g.Enemies = g.Enemies[:0]
+	for _, e := range room.Enemies {
+		g.Enemies = append(g.Enemies, g.readyEnemy(e))
+	}
+	g.waveDelay = 0
+	g.Pickups = append(g.Pickups[:0], room.Pickups...)
+	g.Offers = append(g.Offers[:0], room.Offers...)
+	g.Chests = append(g.Chests[:0], room.Chests...)
+	g.Hazards = append(g.Hazards[:0], room.Hazards...)
+	g.Beams = g.Beams[:0]
+	g.Bullets = g.Bullets[:0]
+	g.EnemyShots = g.EnemyShots[:0]
+	g.BombList = g.BombList[:0]
+	g.Explosions = g.Explosions[:0]
+	g.FX.clear()
+	g.updateRoomClear()
+}
+
+// readyEnemy resets the per-visit state of an enemy entering play.
+func (g *Game) readyEnemy(e Enemy) Enemy {
+	e.Prev = e.Pos
+	if e.Kind == EnemyWander && e.WanderTimer == 0 && e.Alive {
+		e.WanderTimer = 15 + g.rng.Intn(35)
+	}
+	if e.Kind == EnemyShooter && e.ShootCooldown <= 0 {
+		e.ShootCooldown = g.Tune.EnemyShotDelay
+	}
+	if e.Kind == EnemyBoss {
+		if e.ShootCooldown <= 0 {
+			e.ShootCooldown = g.Tune.BossShotDelay
+		}
+		if e.BossRingCD <= 0 {
+			e.BossRingCD = g.Tune.BossRingDelayP3
+		}
+	}
+	return e
+}
+
+func (g *Game) saveCurrentRoomState() {
+	room := g.CurrentRoom()
+	room.Enemies = append(room.Enemies[:0], g.Enemies...)
+	room.Pickups = append(room.Pickups[:0], g.Pickups...)
+	room.Offers = append(room.Offers[:0], g.Offers...)
+	room.Chests = append(room.Chests[:0], g.Chests...)
+}
+
+// Step advances the simulation by exactly one tick.
+func (g *Game) Step(in TickInput) {
+	g.recordTick(in)
+	g.runSpeed = min(g.runSpeed, g.Settings.GameSpeed)
+	g.input = in
+	g.snapshotPrevPositions()
+	g.RunTicks++
+	if g.fireCooldown > 0 {
+		g.fireCooldown--
+	}
+	if g.swapCooldown > 0 {
+		g.swapCooldown--
+	}
+	if g.ItemTextTicks > 0 {
+		g.ItemTextTicks--
+	}
+	if g.StatusTextTick > 0 {
+		g.StatusTextTick--
+	}
+	if g.TransitionTick > 0 {
+		g.TransitionTick--
+	}
+	if g.PlayerInvTicks > 0 {
+		g.PlayerInvTicks--
+	}
+	if g.dashCooldown > 0 {
+		g.dashCooldown--
+	}
+	if g.DashTicks > 0 {
+		g.DashTicks--
+	}
+	if g.bombPlaceCD > 0 {
+		g.bombPlaceCD--
+	}
+	if g.spikeTick > 0 {
+		g.spikeTick--
+	}
+	if g.trinketDropCD > 0 {
+		g.trinketDropCD--
+	}
+	if g.ShakeTick > 0 {
+		g.ShakeTick--
+	}
+	if g.StreakTick > 0 {
+		g.StreakTick--
+		if g.StreakTick == 0 {
+			g.KillStreak = 0
+		}
+	}
+
+	g.updatePlayerMove()
+	g.tryShoot()
+	g.updateFamiliars()
+	g.tryPlaceBomb()
+	g.tryUseActive()
+	g.tryDropTrinket()
+	g.updateBullets()
+	g.updateEnemies()
+	g.updateEnemyShots()
+	g.updateBombs()
+	g.updateExplosions()
+	g.updateBeams()
+	g.updateEffects()
+	g.applyHazardDamage()
+	g.checkPlayerEnemyCollisions()
+	g.checkPlayerEnemyShotCollisions()
+	g.flushModSpawns()
+	g.updateRoomClear()
+	g.updateDoors()
+	g.tryPickupItem()
+	g.tryPickupDrops()
+	g.tryOpenChest()
+	g.tryBuyShopOffer()
+	g.tryRerollShop()
+	g.tryRoomTransition()
+	g.tryDescendFloor()
+}
+
+func (g *Game) updatePlayerMove() {
+	dx, dy := g.input.Move.X, g.input.Move.Y
+	moveDir := Vec2{}
+	if dx != 0 || dy != 0 {
+		l := math.Hypot(dx, dy)
+		moveDir = Vec2{X: dx / l, Y: dy / l}
+		g.lastMoveDir = moveDir
+	}
+	if g.input.Dash && g.dashCooldown == 0 {
+		d := moveDir
+		if d == (Vec2{}) {
+			d = g.lastMoveDir
+		}
+		if d != (Vec2{}) {
+			g.dashDir = d
+			g.DashTicks = g.Tune.DashTicks
+			g.dashCooldown = g.Tune.DashCooldown
+		}
+	}
+	speed := g.MoveSpeed
+	dir := moveDir
+	if g.DashTicks > 0 {
+		speed *= g.Tune.DashSpeedMult
+		dir = g.dashDir
+		g.spawnDashTrail()
+	}
+	g.PlayerPos.X += dir.X * speed
+	g.PlayerPos.Y += dir.Y * speed
+	g.PlayerPos.X = Clamp(g.PlayerPos.X, RoomMargin+PlayerRadius, ScreenW-RoomMargin-PlayerRadius)
+	g.PlayerPos.Y = Clamp(g.PlayerPos.Y, RoomMargin+PlayerRadius, ScreenH-RoomMargin-PlayerRadius)
+}
+
+func (g *Game) tryPlaceBomb() {
+	if !g.hasBomb() || g.bombPlaceCD > 0 || !g.input.Bomb {
+		return
+	}
+	g.spendBomb()
+	g.bombPlaceCD = g.Tune.BombCooldown
+	g.BombList = append(g.BombList, Bomb{Pos: g.PlayerPos, Timer: g.Tune.BombFuse, Active: true})
+	g.emitEvent("bomb_place")
+}
+
+func (g *Game) updateBullets() {
+	for i := range g.Bullets {
+		b := &g.Bullets[i]
+		if !b.Active {
+			continue
+		}
+		g.steerBullet(b)
+		b.Pos.X += b.Vel.X
+		b.Pos.Y += b.Vel.Y
+		if b.Pos.X < RoomMargin || b.Pos.X > ScreenW-RoomMargin || b.Pos.Y < RoomMargin || b.Pos.Y > ScreenH-RoomMargin {
+			g.bulletWall(b)
+			continue
+		}
+		if b.Returning && Distance(b.Pos, g.PlayerPos) <= PlayerRadius {
+			b.Active = false
+			continue
+		}
+		for ei := range g.Enemies {
+			e := &g.Enemies[ei]
+			if !e.Alive {
+				continue
+			}
+			if Distance(b.Pos, e.Pos) <= g.Tune.BulletRadius*math.Sqrt(b.Scale)+g.enemyRadiusOf(*e) {
+				g.spawnHitSparks(b.Pos)
+				dmg := b.Damage
+				if dmg == 0 {
+					dmg = int(math.Ceil(float64(g.rollShotDamage()) * b.Scale))
+				}
+				g.hitEnemy(e, dmg)
+				if b.Flags&WeaponExplosive != 0 {
+					b.Active = false
+					g.explodeBomb(b.Pos)
+					break
+				}
+				if b.Pierce > 0 {
+					b.Pierce--
+				} else {
+					b.Active = false
+				}
+				break
+			}
+		}
+	}
+	alive := g.Bullets[:0]
+	for _, b := range g.Bullets {
+		if b.Active {
+			alive = append(alive, b)
+		}
+	}
+	g.Bullets = alive
+}
+
+func (g *Game) rollShotDamage() int {
+	dmg := float64(g.ShotDamage+g.passiveDamageBonus()+g.trinketDamageBonus()) * g.damageMult
+	if g.rng.Float64() < g.CritChance {
+		dmg = math.Ceil(dmg * g.critMult)
+		g.LastItemText = g.Tr("hit.crit")
+		g.ItemTextTicks = 24
+	}
+	// Fractional damage rounds up with probability equal to the fraction.
+	whole := math.Floor(dmg)
+	if g.rng.Float64() < dmg-whole {
+		whole++
+	}
+	return int(whole)
+}
+
+func (g *Game) updateEnemies() {
+	var spawned []Enemy
+	for i := range g.Enemies {
+		e := &g.Enemies[i]
+		if !e.Alive {
+			continue
+		}
+		switch e.Kind {
+		case EnemyChaser:
+			g.updateChaser(e)
+		case EnemyWander:
+			g.updateWander(e)
+		case EnemyShooter:
+			g.updateShooter(e)
+		case EnemyDasher:
+			g.updateDasher(e)
+		case EnemyBoss:
+			g.updateBoss(e)
+		case EnemySpawner:
+			if m, ok := g.updateSpawner(e); ok {
+				spawned = append(spawned, m)
+			}
+		}
+		if e.Mod != "" {
+			g.updateModEnemy(e)
+		}
+		e.Pos.X += e.Vel.X
+		e.Pos.Y += e.Vel.Y
+		r := g.Tune.EnemyRadius
+		if e.Kind == EnemyBoss {
+			r = g.Tune.BossRadius
+		}
+		if e.Pos.X < RoomMargin+float64(r) || e.Pos.X > ScreenW-RoomMargin-float64(r) {
+			e.Vel.X *= -1
+			e.Pos.X = Clamp(e.Pos.X, RoomMargin+float64(r), ScreenW-RoomMargin-float64(r))
+		}
+		if e.Pos.Y < RoomMargin+float64(r) || e.Pos.Y > ScreenH-RoomMargin-float64(r) {
+			e.Vel.Y *= -1
+			e.Pos.Y = Clamp(e.Pos.Y, RoomMargin+float64(r), ScreenH-RoomMargin-float64(r))
+		}
+	}
+	g.Enemies = append(g.Enemies, spawned...)
+}
+
+func (g *Game) updateChaser(e *Enemy) {
+	dx := g.PlayerPos.X - e.Pos.X
+	dy := g.PlayerPos.Y - e.Pos.Y
+	l := math.Hypot(dx, dy)
+	if l == 0 {
+		return
+	}
+	s := g.Tune.ChaserSpeed * g.enemyDifficultyScale() * enemySpeedMult(e)
+	e.Vel = Vec2{X: dx / l * s, Y: dy / l * s}
+}
+
+func (g *Game) updateWander(e *Enemy) {
+	e.WanderTimer--
+	if e.WanderTimer <= 0 {
+		a := g.rng.Float64() * 2 * math.Pi
+		s := g.Tune.WanderSpeed * g.enemyDifficultyScale() * enemySpeedMult(e)
+		e.Vel = Vec2{X: math.Cos(a) * s, Y: math.Sin(a) * s}
+		e.WanderTimer = 20 + g.rng.Intn(60)
+	}
+}
+
+func (g *Game) updateShooter(e *Enemy) {
+	dx := g.PlayerPos.X - e.Pos.X
+	dy := g.PlayerPos.Y - e.Pos.Y
+	l := math.Hypot(dx, dy)
+	if l > 0 {
+		s := g.Tune.ShooterSpeed * enemySpeedMult(e)
+		e.Vel = Vec2{X: dx / l * s, Y: dy / l * s}
+	}
+	e.ShootCooldown--
+	if e.ShootCooldown <= 0 {
+		if l > 0 {
+			g.EnemyShots = append(g.EnemyShots, EnemyShot{Pos: e.Pos, Prev: e.Pos, Vel: Vec2{X: dx / l * g.Tune.EnemyShotSpeed, Y: dy / l * g.Tune.EnemyShotSpeed}, Active: true, FromBoss: false})
+		}
+		e.ShootCooldown = enemyTimer(e, maxInt(35, int(float64(g.Tune.EnemyShotDelay)/g.enemyDifficultyScale())))
+	}
+}
+
+func (g *Game) updateDasher(e *Enemy) {
+	e.WanderTimer--
+	if e.WanderTimer <= 0 {
+		dx := g.PlayerPos.X - e.Pos.X
+		dy := g.PlayerPos.Y - e.Pos.Y
+		l := math.Hypot(dx, dy)
+		if l > 0 {
+			dashSpeed := 2.8 * g.enemyDifficultyScale() * enemySpeedMult(e)
+			e.Vel = Vec2{X: dx / l * dashSpeed, Y: dy / l * dashSpeed}
+		}
+		e.WanderTimer = enemyTimer(e, 35+g.rng.Intn(40))
+	} else {
+		e.Vel.X *= 0.95
+		e.Vel.Y *= 0.95
+	}
+}
+
+func (g *Game) updateBoss(e *Enemy) {
+	dx := g.PlayerPos.X - e.Pos.X
+	dy := g.PlayerPos.Y - e.Pos.Y
+	l := math.Hypot(dx, dy)
+	speed := g.Tune.BossSpeed
+	if g.isBossPhase2(*e) {
+		speed = g.Tune.BossSpeedP2
+	}
+	if g.isBossPhase3(*e) {
+		speed = g.Tune.BossSpeedP3
+	}
+	if l > 0 {
+		e.Vel = Vec2{X: dx / l * speed, Y: dy / l * speed}
+	}
+
+	if e.ShootWindup > 0 {
+		e.ShootWindup--
+		if e.ShootWindup == 0 {
+			g.fireBossShot(*e)
+		}
+	} else {
+		e.ShootCooldown--
+		if e.ShootCooldown <= 0 {
+			e.ShootWindup = g.Tune.BossWindupTicks
+			if g.isBossPhase2(*e) {
+				e.ShootCooldown = g.Tune.BossShotDelayP2
+			} else {
+				e.ShootCooldown = g.Tune.BossShotDelay
+			}
+		}
+	}
+	if g.isBossPhase3(*e) {
+		e.BossRingCD--
+		if e.BossRingCD <= 0 {
+			g.fireBossRing(*e)
+			e.BossRingCD = g.Tune.BossRingDelayP3
+		}
+	}
+}
+
+func (g *Game) fireBossShot(boss Enemy) {
+	a := math.Atan2(g.PlayerPos.Y-boss.Pos.Y, g.PlayerPos.X-boss.Pos.X)
+	if g.isBossPhase2(boss) {
+		start := -g.Tune.BossSpreadRad
+		step := (g.Tune.BossSpreadRad * 2) / float64(maxInt(1, g.Tune.BossSpreadCount-1))
+		for i := 0; i < g.Tune.BossSpreadCount; i++ {
+			ang := a + start + float64(i)*step
+			g.EnemyShots = append(g.EnemyShots, EnemyShot{Pos: boss.Pos, Prev: boss.Pos, Vel: Vec2{X: math.Cos(ang) * g.Tune.BossShotSpeed, Y: math.Sin(ang) * g.Tune.BossShotSpeed}, Active: true, FromBoss: true})
+		}
+		return
+	}
+	g.EnemyShots = append(g.EnemyShots, EnemyShot{Pos: boss.Pos, Prev: boss.Pos, Vel: Vec2{X: math.Cos(a) * g.Tune.BossShotSpeed, Y: math.Sin(a) * g.Tune.BossShotSpeed}, Active: true, FromBoss: true})
+}
+
+func (g *Game) fireBossRing(boss Enemy) {
+	for i := 0; i < g.Tune.BossRingCountP3; i++ {
+		a := 2 * math.Pi * float64(i) / float64(g.Tune.BossRingCountP3)
+		g.EnemyShots = append(g.EnemyShots, EnemyShot{Pos: boss.Pos, Prev: boss.Pos, Vel: Vec2{X: math.Cos(a) * (g.Tune.BossShotSpeed - 0.8), Y: math.Sin(a) * (g.Tune.BossShotSpeed - 0.8)}, Active: true, FromBoss: true})
+	}
+}
+
+func (g *Game) isBossPhase2(boss Enemy) bool { return boss.HP > 0 && boss.HP <= g.BossMaxHP(boss)/2 }
+
+func (g *Game) isBossPhase3(boss Enemy) bool {
+	return boss.HP > 0 && boss.HP <= g.BossMaxHP(boss)*g.Tune.BossPhase3HP/g.Tune.BossHP
+}
+
+func (g *Game) BossMaxHP(boss Enemy) int {
+	if boss.MaxHP > 0 {
+		return boss.MaxHP
+	}
+	return g.Tune.BossHP
+}
+
+func (g *Game) updateEnemyShots() {
+	for i := range g.EnemyShots {
+		s := &g.EnemyShots[i]
+		if !s.Active {
+			continue
+		}
+		s.Pos.X += s.Vel.X
+		s.Pos.Y += s.Vel.Y
+		if s.Pos.X < RoomMargin || s.Pos.X > ScreenW-RoomMargin || s.Pos.Y < RoomMargin || s.Pos.Y > ScreenH-RoomMargin {
+			s.Active = false
+		}
+	}
+	alive := g.EnemyShots[:0]
+	for _, s := range g.EnemyShots {
+		if s.Active {
+			alive = append(alive, s)
+		}
+	}
+	g.EnemyShots = alive
+}
+
+func (g *Game) updateBombs() {
+	for i := range g.BombList {
+		b := &g.BombList[i]
+		if !b.Active {
+			continue
+		}
+		b.Timer--
+		if b.Timer <= 0 {
+			b.Active = false
+			g.explodeBomb(b.Pos)
+		}
+	}
+	alive := g.BombList[:0]
+	for _, b := range g.BombList {
+		if b.Active {
+			alive = append(alive, b)
+		}
+	}
+	g.BombList = alive
+}
+
+func (g *Game) explodeBomb(pos Vec2) { g.explode(pos, true) }
+
+func (g *Game) explode(pos Vec2, hurtsPlayer bool) {
+	radius := g.Tune.BombRadius * g.bombRadiusMult
+	damage := g.Tune.BombDamage + g.bombDamageBonus
+	g.Explosions = append(g.Explosions, Explosion{Pos: pos, Timer: ExplosionTicks, Radius: radius})
+	for i := range g.Enemies {
+		e := &g.Enemies[i]
+		if !e.Alive {
+			continue
+		}
+		r := g.Tune.EnemyRadius
+		if e.Kind == EnemyBoss {
+			r = g.Tune.BossRadius
+		}
+		if Distance(pos, e.Pos) <= radius+float64(r) {
+			e.HP -= damage
+			g.runDamageDealt += damage
+			if e.HP <= 0 {
+				e.Alive = false
+				g.onEnemyKilled(*e)
+			}
+		}
+	}
+	for i := range g.Chests {
+		c := &g.Chests[i]
+		if !c.Opened && Distance(pos, c.Pos) <= radius+12 {
+			g.openChest(c)
+		}
+	}
+	g.revealSecretDoors(pos, radius)
+	g.bombShopkeeper(pos, radius)
+	if hurtsPlayer && Distance(pos, g.PlayerPos) <= radius+PlayerRadius {
+		g.damagePlayer(1)
+	}
+	g.ShakeTick = 8
+	g.ShakeMag = 5
+	g.emitEvent("bomb_explode")
+}
+
+func (g *Game) updateExplosions() {
+	alive := g.Explosions[:0]
+	for _, ex := range g.Explosions {
+		ex.Timer--
+		if ex.Timer > 0 {
+			alive = append(alive, ex)
+		}
+	}
+	g.Explosions = alive
+}
+
+func (g *Game) onEnemyKilled(enemy Enemy) {
+	g.spawnDeathBurst(enemy)
+	g.killCount++
+	g.KillStreak++
+	g.StreakTick = StreakTimeoutTicks
+	base := 10
+	if enemy.Kind == EnemyWander {
+		base = 15
+	}
+	if enemy.Kind == EnemyShooter {
+		base = 20
+	}
+	if enemy.Kind == EnemySpawner {
+		base = 30
+	}
+	if enemy.Minion {
+		base = 3
+	}
+	if enemy.Champion != ChampionNone {
+		base *= 2
+	}
+	if enemy.Kind == EnemyBoss {
+		base = 300
+		g.RunsCompleted++
+	}
+	mult := 1.0 + math.Min(float64(g.KillStreak-1)*0.12, 1.2)
+	g.Score += int(float64(base) * mult)
+	g.updateBestScore()
+	g.modHook("on_kill", g.enemyEvent(-1, enemy))
+	if enemy.Kind == EnemyBoss {
+		g.split(g.bossSplitName())
+		g.SaveMeta()
+		g.checkBossRushComplete()
+		return
+	}
+	if enemy.Champion != ChampionNone {
+		g.onChampionKilled(enemy)
+		return
+	}
+	if !enemy.Minion {
+		g.dropLoot(enemy.Pos, false)
+	}
+}
+
+func (g *Game) applyHazardDamage() {
+	if g.spikeTick > 0 || g.DashTicks > 0 {
+		return
+	}
+	for _, h := range g.Hazards {
+		if Distance(g.PlayerPos, h.Pos) <= h.R+PlayerRadius {
+			g.damagePlayer(1)
+			g.spikeTick = spikeDamageTick
+			return
+		}
+	}
+}
+
+func (g *Game) checkPlayerEnemyCollisions() {
+	if g.PlayerInvTicks > 0 {
+		return
+	}
+	for _, e := range g.Enemies {
+		if !e.Alive {
+			continue
+		}
+		r := g.Tune.EnemyRadius
+		if e.Kind == EnemyBoss {
+			r = g.Tune.BossRadius
+		}
+		if Distance(g.PlayerPos, e.Pos) <= PlayerRadius+float64(r) {
+			g.damagePlayer(g.Tune.ContactDamage)
+			return
+		}
+	}
+}
+
+func (g *Game) checkPlayerEnemyShotCollisions() {
+	if g.PlayerInvTicks > 0 {
+		return
+	}
+	for i := range g.EnemyShots {
+		s := &g.EnemyShots[i]
+		if !s.Active {
+			continue
+		}
+		r := g.Tune.EnemyShotRadius
+		dmg := 1
+		if s.FromBoss {
+			r = g.Tune.BossShotRadius
+			dmg = g.Tune.BossShotDamage
+		}
+		if Distance(g.PlayerPos, s.Pos) <= PlayerRadius+float64(r) {
+			s.Active = false
+			g.damagePlayer(dmg)
+			return
+		}
+	}
+}
+
+func (g *Game) damagePlayer(amount int) {
+	if g.DashTicks > 0 || g.godMode {
+		return
+	}
+	if g.ShieldCharges > 0 {
+		g.ShieldCharges--
+		g.StatusText = g.Tr("shield.blocked")
+		g.StatusTextTick = 40
+		return
+	}
+	g.runDamageTaken += amount
+	soak := minInt(g.SoulHP, amount)
+	g.SoulHP -= soak
+	g.PlayerHP -= amount - soak
+	if g.PlayerHP < 0 {
+		g.PlayerHP = 0
+	}
+	g.PlayerInvTicks = g.Tune.HurtCooldown
+	g.KillStreak = 0
+	g.StreakTick = 0
+	if g.PlayerHP == 0 {
+		g.Deaths++
+		g.recordEndlessFloor()
+		g.SaveMeta()
+		g.recordLeaderboard("death")
+		g.saveRunTelemetry("death")
+		g.finishSplits()
+	}
+	g.onTrinketHurt()
+	g.ShakeTick = 10
+	g.ShakeMag = 4
+	g.emitEvent("player_hit")
+	g.modHook("on_hit", map[string]any{"target": "player", "damage": amount, "hp": g.PlayerHP})
+}
+
+func (g *Game) updateRoomClear() {
+	for _, e := range g.Enemies {
+		if e.Alive {
+			g.RoomClear = false
+			return
+		}
+	}
+	if g.updateWaves() {
+		g.RoomClear = false
+		return
+	}
+	room := g.CurrentRoom()
+	if !g.RoomClear {
+		g.RoomClear = true
+		if !room.Cleared {
+			g.onRoomCleared()
+		}
+	}
+	room.Cleared = true
+}
+
+func (g *Game) CurrentRoom() *Room { return g.Rooms[g.CurrentRoomID] }
+
+func (g *Game) roomInDir(dx, dy int) (int, bool) {
+	cur := g.CurrentRoom()
+	id, ok := g.GridToRoomID[[2]int{cur.GridX + dx, cur.GridY + dy}]
+	return id, ok
+}
+
+func (g *Game) roomNeedsClearForBoss(id int) bool {
+	r := g.Rooms[id]
+	return r.Type == RoomStart || r.Type == RoomCombat
+}
+
+func (g *Game) allNonBossRoomsCleared() bool {
+	for id, room := range g.Rooms {
+		if room.Type == RoomBoss || !g.roomNeedsClearForBoss(id) {
+			continue
+		}
+		if len(room.Waves) > 0 {
+			return false
+		}
+		enemies := room.Enemies
+		if id == g.CurrentRoomID {
+			enemies = g.Enemies
+		}
+		for _, e := range enemies {
+			if e.Alive {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (g *Game) tryPickupItem() {
+	if !g.RoomClear {
+		return
+	}
+	room := g.CurrentRoom()
+	if room.Reward.Taken || Distance(g.PlayerPos, room.Reward.Pos) > PlayerRadius+ItemRadius {
+		return
+	}
+	room.Reward.Taken = true
+	if room.Reward.Mod != "" {
+		g.applyModItem(room.Reward.Mod)
+		return
+	}
+	g.applyItem(room.Reward.Kind)
+}
+
+func (g *Game) tryPickupDrops() {
+	for i := range g.Pickups {
+		p := &g.Pickups[i]
+		if !p.Active || Distance(g.PlayerPos, p.Pos) > PlayerRadius+g.Tune.PickupRadius {
+			continue
+		}
+		if p.Kind == PickupTrinket && g.trinketDropCD > 0 {
+			continue
+		}
+		p.Active = false
+		g.collectPickup(*p)
+	}
+	alive := g.Pickups[:0]
+	for _, p := range g.Pickups {
+		if p.Active {
+			alive = append(alive, p)
+		}
+	}
+	g.Pickups = alive
+}
+
+func (g *Game) tryOpenChest() {
+	if !g.input.Chest {
+		return
+	}
+	for i := range g.Chests {
+		c := &g.Chests[i]
+		if c.Opened || Distance(g.PlayerPos, c.Pos) > shopInteractRadius {
+			continue
+		}
+		if !g.spendKey() {
+			g.StatusText = g.Tr("door.need_key")
+			g.StatusTextTick = 80
+			return
+		}
+		g.openChest(c)
+		return
+	}
+}
+
+func (g *Game) openChest(c *Chest) {
+	if c.Opened {
+		return
+	}
+	c.Opened = true
+	drop := g.rollDrop(chestDrops, g.Tune.ChestDrops, true)
+	g.spawnDrop(drop, c.Pos)
+	g.LastItemText = g.Tr(drop.Text)
+	g.updateBestScore()
+	g.ItemTextTicks = itemTextDuration
+}
+
+// unranked runs keep no records: console cheats, or any stretch played below
+// full game speed.
+func (g *Game) unranked() bool { return g.Cheated || g.runSpeed < MaxGameSpeed }
+
+// updateBestScore records a new best unless the run is unranked.
+func (g *Game) updateBestScore() {
+	if g.unranked() || g.Score <= g.BestScore {
+		return
+	}
+	g.BestScore = g.Score
+	g.SaveMeta()
+}
+
+// applyItem collects a picked-up item: its effect, the pickup text and the
+// mods' on_pickup hook.
+func (g *Game) applyItem(kind ItemType) {
+	g.CollectedItems = append(g.CollectedItems, kind)
+	g.LastItemText = g.Tr(g.grantItem(kind))
+	g.ItemTextTicks = itemTextDuration
+	g.modHook("on_pickup", map[string]any{"kind": "item", "name": itemNames[kind]})
+}
+
+// grantItem applies the effect of an item and returns its text key. Starting
+// items go through here alone, so they neither show nor count as pickups.
+func (g *Game) grantItem(kind ItemType) string {
+	switch kind {
+	case ItemDamage:
+		g.ShotDamage++
+		return "item.damage"
+	case ItemFireRate:
+		if g.ShotCooldownBase > 4 {
+			g.ShotCooldownBase -= 2
+		}
+		return "item.fire_rate"
+	case ItemSpeed:
+		g.MoveSpeed += 0.35
+		return "item.speed"
+	case ItemHeal:
+		g.PlayerHP = minInt(g.MaxHP, g.PlayerHP+1)
+		return "item.heal"
+	case ItemCrit:
+		g.CritChance = Clamp(g.CritChance+0.10, 0, 0.8)
+		return "item.crit"
+	case ItemPierce:
+		g.pierceCount = minInt(3, g.pierceCount+1)
+		return "item.pierce"
+	case ItemMultiShot:
+		g.multiShot = true
+		return "item.multishot"
+	case ItemBombMaster:
+		g.bombRadiusMult = Clamp(g.bombRadiusMult+0.15, 1.0, 1.9)
+		g.bombDamageBonus += 2
+		return "item.bomb_master"
+	case ItemLuck:
+		g.luck = Clamp(g.luck+0.08, 0, 0.6)
+		g.CritChance = Clamp(g.CritChance+0.03, 0, 0.9)
+		return "item.luck"
+	case ItemShield:
+		g.MaxShieldCharges = minInt(3, g.MaxShieldCharges+1)
+		g.ShieldCharges = g.MaxShieldCharges
+		return "item.shield"
+	case ItemCompass:
+		g.hasCompass = true
+		return "item.compass"
+	case ItemLaser, ItemChargeShot, ItemHoming, ItemBoomerang, ItemExplosive:
+		g.Weapon |= weaponItems[kind]
+		g.ChargeTicks = 0
+		return weaponItemKeys[kind]
+	case ItemBuddy, ItemSentry, ItemHalo, ItemMagnet:
+		g.addFamiliar(FamiliarItems[kind])
+		return familiarItemKeys[kind]
+	}
+	return ""
+}
+
+func (g *Game) tryRoomTransition() {
+	if g.swapCooldown > 0 {
+		return
+	}
+	var dx, dy int
+	var spawn Vec2
+	nearLeft := g.PlayerPos.X <= RoomMargin+PlayerRadius+1 && math.Abs(g.PlayerPos.Y-ScreenH/2) <= DoorHalf
+	nearRight := g.PlayerPos.X >= ScreenW-RoomMargin-PlayerRadius-1 && math.Abs(g.PlayerPos.Y-ScreenH/2) <= DoorHalf
+	nearUp := g.PlayerPos.Y <= RoomMargin+PlayerRadius+1 && math.Abs(g.PlayerPos.X-ScreenW/2) <= DoorHalf
+	nearDown := g.PlayerPos.Y >= ScreenH-RoomMargin-PlayerRadius-1 && math.Abs(g.PlayerPos.X-ScreenW/2) <= DoorHalf
+	switch {
+	case nearLeft:
+		dx = -1
+		spawn = Vec2{X: ScreenW - RoomMargin - PlayerRadius - 8, Y: ScreenH / 2}
+	case nearRight:
+		dx = 1
+		spawn = Vec2{X: RoomMargin + PlayerRadius + 8, Y: ScreenH / 2}
+	case nearUp:
+		dy = -1
+		spawn = Vec2{X: ScreenW / 2, Y: ScreenH - RoomMargin - PlayerRadius - 8}
+	case nearDown:
+		dy = 1
+		spawn = Vec2{X: ScreenW / 2, Y: RoomMargin + PlayerRadius + 8}
+	default:
+		return
+	}
+	nextID, ok := g.roomInDir(dx, dy)
+	if !ok {
+		return
+	}
+	door := g.DoorToward(dx, dy)
+	if door == nil || !g.tryPassDoor(door) {
+		return
+	}
+	g.swapRoom(g.mazeRoom(nextID), spawn)
+}
+
+func (g *Game) swapRoom(nextRoomID int, spawn Vec2) {
+	g.saveCurrentRoomState()
+	g.CurrentRoomID = nextRoomID
+	g.VisitedRooms[nextRoomID] = true
+	g.runRoomsVisited++
+	g.LoadCurrentRoom()
+	g.PlayerPos = spawn
+	g.PlayerPrevPos = spawn
+	g.swapCooldown = roomSwapCooldown
+	g.TransitionTick = TransitionTicksMax
+	g.placeFamiliars()
+}
+
+func (g *Game) FloorCleared() bool {
+	if g.Mode == ModeBossRush || g.CurrentRoomID != g.bossRoomID {
+		return false
+	}
+	return g.RoomClear
+}
+
+func (g *Game) tryDescendFloor() {
+	if !g.FloorCleared() {
+		return
+	}
+	if Distance(g.PlayerPos, Vec2{X: ScreenW / 2, Y: ScreenH / 2}) > 28 {
+		return
+	}
+	if !g.input.Descend {
+		return
+	}
+	g.startNextFloor()
+}
+
+func (g *Game) startNextFloor() {
+	g.split(fmt.Sprintf(splitFloor, g.Floor))
+	g.saveRunTelemetry("floor_clear")
+	g.floorsCleared++
+	g.enterFloor(g.Floor + 1)
+}
+
+// enterFloor builds floor n and puts the player in its start room. Unlike
+// startNextFloor it records no clear, so the console can jump with it.
+func (g *Game) enterFloor(n int) {
+	g.saveCurrentRoomState()
+	g.Floor = n
+	g.ShopRerolls = 0
+	g.TransitionTick = TransitionTicksMax
+	g.PlayerInvTicks = 0
+	g.PlayerHP = minInt(g.MaxHP, g.PlayerHP+1)
+	g.rollCurse()
+
+	g.initRoomsProcedural()
+	g.VisitedRooms = map[int]bool{g.CurrentRoomID: true}
+	g.runRoomsVisited++
+	g.LoadCurrentRoom()
+	g.PlayerPos = Vec2{X: ScreenW / 2, Y: ScreenH / 2}
+	g.PlayerPrevPos = g.PlayerPos
+	g.placeFamiliars()
+	g.StatusText = g.Tr("floor.welcome", g.Floor)
+	if g.Curse != CurseNone {
+		g.StatusText = g.Tr("floor.cursed", g.Floor, g.CurseName(g.Curse))
+	}
+	g.StatusTextTick = 120
+}
+
+// snapshotPrevPositions remembers where moving things were before this tick
+// so Draw can interpolate between the last two ticks.
+func (g *Game) snapshotPrevPositions() {
+	g.PlayerPrevPos = g.PlayerPos
+	for i := range g.Bullets {
+		g.Bullets[i].Prev = g.Bullets[i].Pos
+	}
+	for i := range g.EnemyShots {
+		g.EnemyShots[i].Prev = g.EnemyShots[i].Pos
+	}
+	for i := range g.Enemies {
+		g.Enemies[i].Prev = g.Enemies[i].Pos
+	}
+	for i := range g.Familiars {
+		g.Familiars[i].Prev = g.Familiars[i].Pos
+	}
+}
+
+func (g *Game) emitEvent(_ string) {
+	// Placeholder hook for future SFX integration.
+}
+
+func (g *Game) AliveEnemyCount() int {
+	n := 0
+	for _, e := range g.Enemies {
+		if e.Alive {
+			n++
+		}
+	}
+	return n
+}
+
+func (g *Game) AllRoomsCleared() bool {
+	for id, room := range g.Rooms {
+		if len(room.Waves) > 0 {
+			return false
+		}
+		enemies := room.Enemies
+		if id == g.CurrentRoomID {
+			enemies = g.Enemies
+		}
+		for _, e := range enemies {
+			if e.Alive {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (g *Game) BossInPhase2() bool {
+	if g.CurrentRoom().Type != RoomBoss {
+		return false
+	}
+	for _, e := range g.Enemies {
+		if e.Kind == EnemyBoss && e.Alive {
+			return g.isBossPhase2(e)
+		}
+	}
+	return false
+}
+
+func (g *Game) BossInPhase3() bool {
+	if g.CurrentRoom().Type != RoomBoss {
+		return false
+	}
+	for _, e := range g.Enemies {
+		if e.Kind == EnemyBoss && e.Alive {
+			return g.isBossPhase3(e)
+		}
+	}
+	return false
+}
+
+func (g *Game) enemyDifficultyScale() float64 {
+	cleared := 0
+	for id, room := range g.Rooms {
+		if room.Type == RoomBoss || room.Type == RoomShop {
+			continue
+		}
+		enemies := room.Enemies
+		if id == g.CurrentRoomID {
+			enemies = g.Enemies
+		}
+		alive := false
+		for _, e := range enemies {
+			if e.Alive {
+				alive = true
+				break
+			}
+		}
+		if !alive {
+			cleared++
+		}
+	}
+	metaScale := 1 + float64(g.RunsCompleted)*0.02
+	return (1 + float64(cleared)*0.05) * metaScale * g.modeSpeedScale()
+}
+
+func (g *Game) RunRank() string {
+	s := g.Score
+	switch {
+	case s >= 1200:
+		return "S"
+	case s >= 850:
+		return "A"
+	case s >= 500:
+		return "B"
+	case s >= 250:
+		return "C"
+	default:
+		return "D"
+	}
+}
+
+func (g *Game) metaPath() string { return filepath.Join(".", "save_meta.json") }
+
+func (g *Game) loadMeta() {
+	data, err := os.ReadFile(g.metaPath())
+	if err != nil {
+		return
+	}
+	var m MetaSave
+	if err := json.Unmarshal(data, &m); err != nil {
+		return
+	}
+	g.RunsCompleted = m.RunsCompleted
+	g.Deaths = m.Deaths
+	g.CharacterID = m.Character
+	g.Mode = runModeByName(m.Mode)
+	g.ModeBest = m.ModeBest
+	if g.ModeBest == nil {
+		g.ModeBest = map[string]int{}
+	}
+	if g.ModeBest[ModeNormal.String()] < m.BestScore {
+		g.ModeBest[ModeNormal.String()] = m.BestScore
+	}
+	g.EndlessFloors = m.EndlessFloors
+}
+
+func (g *Game) SaveMeta() {
+	if g.ModeBest == nil {
+		g.ModeBest = map[string]int{}
+	}
+	g.ModeBest[g.Mode.String()] = g.BestScore
+	if g.Playback {
+		return
+	}
+	m := MetaSave{
+		BestScore:     g.ModeBest[ModeNormal.String()],
+		RunsCompleted: g.RunsCompleted,
+		Deaths:        g.Deaths,
+		Character:     g.CharacterID,
+		Mode:          g.Mode.String(),
+		ModeBest:      g.ModeBest,
+		EndlessFloors: g.EndlessFloors,
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(g.metaPath(), data, 0644)
+}
+
+func (g *Game) telemetryPath() string { return filepath.Join(".", "run_telemetry.jsonl") }
+
+func (g *Game) saveRunTelemetry(result string) {
+	if g.Playback {
+		return
+	}
+	entry := RunTelemetry{
+		Timestamp:       time.Now().Format(time.RFC3339),
+		Seed:            g.RunSeed,
+		Floor:           g.Floor,
+		Generator:       g.FloorGenName,
+		Character:       g.CharacterID,
+		Mode:            g.Mode.String(),
+		Score:           g.Score,
+		RoomsVisited:    g.runRoomsVisited,
+		EnemiesDefeated: g.killCount,
+		DamageTaken:     g.runDamageTaken,
+		DamageDealt:     g.runDamageDealt,
+		RunSeconds:      g.RunTicks / SimTPS,
+		Rank:            g.RunRank(),
+		Result:          result,
+
+		ShopkeepersKilled: g.keepersKilled,
+		Purchases:         g.purchases,
+		Cheated:           g.Cheated,
+		GameSpeed:         g.runSpeed,
+		Familiars:         g.familiarList(),
+		Mods:              g.modList(),
+		ModItems:          g.collectedMods,
+		Curses:            g.curseLog,
+		Trinkets:          g.trinketLog,
+		LayoutRelaxed:     g.relaxedFloors,
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(g.telemetryPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_, _ = f.Write(append(data, '\n'))
+}
+
+func FormatRunTime(ticks int) string {
+	total := ticks / SimTPS
+	m := total / 60
+	s := total % 60
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func Clamp(v, minV, maxV float64) float64 {
+	if v < minV {
+		return minV
+	}
+	if v > maxV {
+		return maxV
+	}
+	return v
+}
+
+func Distance(a, b Vec2) float64 { return math.Hypot(a.X-b.X, a.Y-b.Y) }
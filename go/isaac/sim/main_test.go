@@ -0,0 +1,23 @@
+package sim
+
+import (
+	"os"
+	"testing"
+)
+
+// inTempDir runs the test from an empty directory, so saves on the machine
+// do not leak in and nothing the game writes leaks out.
+func inTempDir(t *testing.T) string {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	t.Setenv("ISAAC_LANG", "en")
+	return dir
+}
@@ -0,0 +1,28 @@
+package sim
+
+// RoomKnown is the fog of war: a room shows on the map once visited, when a
+// visible door leads to it from a visited room, or always with the compass.
+func (g *Game) RoomKnown(id int) bool {
+	room := g.Rooms[id]
+	if g.VisitedRooms[id] {
+		return true
+	}
+	if room.Type == RoomSecret {
+		return false
+	}
+	if g.hasCompass {
+		return true
+	}
+	a := [2]int{room.GridX, room.GridY}
+	for _, d := range LayoutDirs {
+		b := [2]int{a[0] + d[0], a[1] + d[1]}
+		other, ok := g.GridToRoomID[b]
+		if !ok || !g.VisitedRooms[other] {
+			continue
+		}
+		if door := g.Doors[doorKey(a, b)]; door != nil && door.State != DoorSecret {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package sim
+
+import "sort"
+
+type RunMode int
+
+const (
+	ModeNormal RunMode = iota
+	ModeBossRush
+	ModeEndless
+)
+
+const (
+	bossRushLength     = 5
+	bossRushHPStep     = 0.35
+	endlessHPStep      = 0.25
+	endlessSpeedStep   = 0.06
+	endlessLeaderboard = 10
+)
+
+var RunModes = []RunMode{ModeNormal, ModeBossRush, ModeEndless}
+
+func (m RunMode) String() string {
+	switch m {
+	case ModeBossRush:
+		return "boss_rush"
+	case ModeEndless:
+		return "endless"
+	}
+	return "normal"
+}
+
+func (g *Game) ModeLabel(m RunMode) string { return g.Tr("mode." + m.String()) }
+
+func runModeByName(name string) RunMode {
+	for _, m := range RunModes {
+		if m.String() == name {
+			return m
+		}
+	}
+	return ModeNormal
+}
+
+// modeHPScale multiplies enemy HP. Endless grows it every floor.
+func (g *Game) modeHPScale() float64 {
+	if g.Mode == ModeEndless {
+		return 1 + endlessHPStep*float64(g.Floor-1)
+	}
+	return 1
+}
+
+func (g *Game) modeSpeedScale() float64 {
+	if g.Mode == ModeEndless {
+		return 1 + endlessSpeedStep*float64(g.Floor-1)
+	}
+	return 1
+}
+
+// initBossRushRooms lays out start, boss, shop, boss, ... as a single corridor
+// so each boss room is the only way to the next shop.
+func (g *Game) initBossRushRooms() {
+	g.FloorGenName = "boss_rush"
+	count := bossRushLength*2 - 1
+	cells := snakeCells(count + 1)
+
+	g.Rooms = make(map[int]*Room, len(cells))
+	g.GridToRoomID = make(map[[2]int]int, len(cells))
+	for id, c := range cells {
+		r := &Room{ID: id, GridX: c[0], GridY: c[1]}
+		switch {
+		case id == 0:
+			r.Type = RoomStart
+			r.Reward = Item{Taken: true}
+			g.CurrentRoomID = id
+		case id%2 == 1:
+			r.Type = RoomBoss
+			g.bossRoomID = id
+			g.populateBossRoom(r)
+			boss := &r.Enemies[0]
+			boss.HP = int(float64(boss.HP) * (1 + bossRushHPStep*float64(id/2)))
+			boss.MaxHP = boss.HP
+		default:
+			r.Type = RoomShop
+			g.shopRoomID = id
+			g.populateShopRoom(r)
+		}
+		g.Rooms[id] = r
+		g.GridToRoomID[c] = id
+	}
+	g.treasureRoomID = -1
+	g.bossDoorsOpen = false
+	g.initDoors()
+}
+
+// snakeCells walks rows left to right and back, leaving an empty row between
+// passes so the corridor never touches itself.
+func snakeCells(n int) [][2]int {
+	cells := make([][2]int, 0, n)
+	x, y, dir := -layoutBound, -layoutBound, 1
+	for len(cells) < n {
+		cells = append(cells, [2]int{x, y})
+		if x+dir > layoutBound || x+dir < -layoutBound {
+			if len(cells) < n {
+				cells = append(cells, [2]int{x, y + 1})
+			}
+			y += 2
+			dir = -dir
+			continue
+		}
+		x += dir
+	}
+	return cells
+}
+
+func (g *Game) bossRushBossesLeft() int {
+	left := 0
+	for id, room := range g.Rooms {
+		if room.Type != RoomBoss {
+			continue
+		}
+		enemies := room.Enemies
+		if id == g.CurrentRoomID {
+			enemies = g.Enemies
+		}
+		for _, e := range enemies {
+			if e.Alive {
+				left++
+			}
+		}
+	}
+	return left
+}
+
+func (g *Game) checkBossRushComplete() {
+	if g.Mode != ModeBossRush || g.RushComplete || g.bossRushBossesLeft() > 0 {
+		return
+	}
+	g.RushComplete = true
+	g.StatusText = g.Tr("rush.complete")
+	g.StatusTextTick = 240
+	g.SaveMeta()
+	g.recordLeaderboard("boss_rush_clear")
+	g.saveRunTelemetry("boss_rush_clear")
+	g.finishSplits()
+}
+
+func (g *Game) recordEndlessFloor() {
+	if g.Mode != ModeEndless || g.unranked() {
+		return
+	}
+	g.EndlessFloors = append(g.EndlessFloors, g.Floor)
+	sort.Sort(sort.Reverse(sort.IntSlice(g.EndlessFloors)))
+	if len(g.EndlessFloors) > endlessLeaderboard {
+		g.EndlessFloors = g.EndlessFloors[:endlessLeaderboard]
+	}
+}
@@ -0,0 +1,547 @@
+package sim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+const (
+	modManifestName = "mod.json"
+	modCallBudget   = 20 * time.Millisecond
+	modItemChance   = 0.2
+)
+
+var ModItemColor = color.RGBA{R: 90, G: 200, B: 180, A: 255}
+
+// ModManifest is mods/<dir>/mod.json. Mods load by ascending priority, then
+// id, but never before the mods they require; a mod whose requirements are
+// missing, disabled or circular is skipped.
+type ModManifest struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	Script   string   `json:"script"`
+	Priority int      `json:"priority"`
+	Requires []string `json:"requires"`
+	Disabled bool     `json:"disabled"`
+
+	dir string
+}
+
+// Mod is a loaded mod with its own Lua state. A mod that errors at run time
+// is switched off for the rest of the session.
+type Mod struct {
+	Manifest ModManifest
+	L        *lua.LState
+	Failed   bool
+}
+
+// ModItem is a passive item registered by a script with isaac.register_item.
+type ModItem struct {
+	ID      string
+	Name    string
+	mod     *Mod
+	collect *lua.LFunction
+}
+
+// ModEnemy is an enemy registered with isaac.register_enemy. It moves like
+// its base kind; the optional update function can steer it every tick.
+type ModEnemy struct {
+	ID     string
+	Base   EnemyType
+	HP     int
+	Weight float64
+	Tint   color.RGBA
+	mod    *Mod
+	update *lua.LFunction
+}
+
+func modsDir() string { return filepath.Join(".", "mods") }
+
+func readModManifests(dir string) []ModManifest {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var list []ModManifest
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name(), modManifestName))
+		if err != nil {
+			continue
+		}
+		var m ModManifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			log.Printf("mod %s: bad manifest: %v", e.Name(), err)
+			continue
+		}
+		if m.ID == "" {
+			m.ID = e.Name()
+		}
+		if m.Script == "" {
+			m.Script = "main.lua"
+		}
+		m.dir = filepath.Join(dir, e.Name())
+		list = append(list, m)
+	}
+	return list
+}
+
+// orderMods applies the load-order rules and reports the mods it had to drop.
+func orderMods(list []ModManifest) ([]ModManifest, []string) {
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Priority != list[j].Priority {
+			return list[i].Priority < list[j].Priority
+		}
+		return list[i].ID < list[j].ID
+	})
+	var skipped []string
+	pending := make([]ModManifest, 0, len(list))
+	seen := map[string]bool{}
+	for _, m := range list {
+		switch {
+		case m.Disabled:
+		case seen[m.ID]:
+			skipped = append(skipped, m.ID+" (duplicate id)")
+		default:
+			seen[m.ID] = true
+			pending = append(pending, m)
+		}
+	}
+	loaded := map[string]bool{}
+	var out []ModManifest
+	for progress := true; progress; {
+		progress = false
+		for i := 0; i < len(pending); i++ {
+			m := pending[i]
+			ready := true
+			for _, r := range m.Requires {
+				ready = ready && loaded[r]
+			}
+			if ready {
+				out = append(out, m)
+				loaded[m.ID] = true
+				pending = append(pending[:i], pending[i+1:]...)
+				progress = true
+				break
+			}
+		}
+	}
+	for _, m := range pending {
+		skipped = append(skipped, m.ID+" (requires "+strings.Join(m.Requires, ", ")+")")
+	}
+	return out, skipped
+}
+
+func (g *Game) loadMods() {
+	manifests, skipped := orderMods(readModManifests(modsDir()))
+	for _, s := range skipped {
+		log.Printf("mod skipped: %s", s)
+	}
+	for _, m := range manifests {
+		mod := &Mod{Manifest: m, L: g.newModState(m)}
+		g.mods = append(g.mods, mod)
+		if err := mod.guarded(func() error { return mod.L.DoFile(filepath.Join(m.dir, m.Script)) }); err != nil {
+			g.modFailed(mod, err)
+		}
+	}
+}
+
+// guarded runs f with the per-call time budget, so a runaway loop in a
+// script errors out instead of freezing the game.
+func (mod *Mod) guarded(f func() error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), modCallBudget)
+	defer cancel()
+	mod.L.SetContext(ctx)
+	defer mod.L.RemoveContext()
+	return f()
+}
+
+func (g *Game) modFailed(mod *Mod, err error) {
+	mod.Failed = true
+	log.Printf("mod %s: %v", mod.Manifest.ID, err)
+	g.StatusText = g.Tr("mod.error", mod.Manifest.ID)
+	g.StatusTextTick = 180
+}
+
+// newModState opens a Lua state with only the safe standard libraries: no
+// io, os, package or file loading. Randomness goes through the run RNG so a
+// seed plays out the same with the same mods.
+func (g *Game) newModState(m ModManifest) *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{{lua.BaseLibName, lua.OpenBase}, {lua.TabLibName, lua.OpenTable}, {lua.StringLibName, lua.OpenString}, {lua.MathLibName, lua.OpenMath}} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "require", "module", "collectgarbage"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+	api := L.SetFuncs(L.NewTable(), g.modAPI(m))
+	L.SetGlobal("isaac", api)
+	L.SetGlobal("print", api.RawGetString("log"))
+	if math, ok := L.GetGlobal("math").(*lua.LTable); ok {
+		math.RawSetString("random", api.RawGetString("random"))
+		math.RawSetString("randomseed", lua.LNil)
+	}
+	return L
+}
+
+// modCall runs a script function under the time budget. Hooks do not nest:
+// anything a hook does that would fire another hook stays quiet.
+func (g *Game) modCall(mod *Mod, fn *lua.LFunction, args ...lua.LValue) lua.LValue {
+	if mod.Failed {
+		return lua.LNil
+	}
+	g.modDepth++
+	defer func() { g.modDepth-- }()
+	err := mod.guarded(func() error { return mod.L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, args...) })
+	if err != nil {
+		g.modFailed(mod, err)
+		return lua.LNil
+	}
+	ret := mod.L.Get(-1)
+	mod.L.Pop(1)
+	return ret
+}
+
+// modHook calls the named global function of every mod, in load order.
+func (g *Game) modHook(hook string, ev map[string]any) {
+	if len(g.mods) == 0 || g.modDepth > 0 {
+		return
+	}
+	for _, mod := range g.mods {
+		if fn, ok := mod.L.GetGlobal(hook).(*lua.LFunction); ok && !mod.Failed {
+			g.modCall(mod, fn, luaTable(mod.L, ev))
+		}
+	}
+}
+
+func luaTable(L *lua.LState, fields map[string]any) *lua.LTable {
+	t := L.NewTable()
+	for k, v := range fields {
+		switch v := v.(type) {
+		case string:
+			t.RawSetString(k, lua.LString(v))
+		case int:
+			t.RawSetString(k, lua.LNumber(v))
+		case float64:
+			t.RawSetString(k, lua.LNumber(v))
+		case bool:
+			t.RawSetString(k, lua.LBool(v))
+		}
+	}
+	return t
+}
+
+func enemyKindName(k EnemyType) string {
+	for name, kind := range consoleEnemies {
+		if kind == k {
+			return name
+		}
+	}
+	return "enemy"
+}
+
+func (g *Game) enemyEvent(i int, e Enemy) map[string]any {
+	kind := enemyKindName(e.Kind)
+	if e.Mod != "" {
+		kind = e.Mod
+	}
+	ev := map[string]any{"kind": kind, "x": e.Pos.X, "y": e.Pos.Y, "hp": e.HP, "champion": e.Champion != ChampionNone, "minion": e.Minion}
+	if i >= 0 {
+		ev["index"] = i + 1
+	}
+	return ev
+}
+
+func (g *Game) enemyIndex(e *Enemy) int {
+	for i := range g.Enemies {
+		if &g.Enemies[i] == e {
+			return i
+		}
+	}
+	return -1
+}
+
+// modAPI is the isaac table a script sees. Spawns, tears and shots made from
+// a script are queued and join the room at the end of the tick, so a hook
+// never grows a slice the simulation is walking.
+func (g *Game) modAPI(m ModManifest) map[string]lua.LGFunction {
+	prefix := m.ID + ":"
+	return map[string]lua.LGFunction{
+		"log": func(L *lua.LState) int {
+			parts := make([]string, 0, L.GetTop())
+			for i := 1; i <= L.GetTop(); i++ {
+				parts = append(parts, L.ToStringMeta(L.Get(i)).String())
+			}
+			log.Printf("[%s] %s", m.ID, strings.Join(parts, " "))
+			return 0
+		},
+		"message": func(L *lua.LState) int {
+			g.LastItemText = L.CheckString(1)
+			g.ItemTextTicks = itemTextDuration
+			return 0
+		},
+		"random": func(L *lua.LState) int {
+			if n := L.OptInt(1, 0); n > 0 {
+				L.Push(lua.LNumber(1 + g.rng.Intn(n)))
+			} else {
+				L.Push(lua.LNumber(g.rng.Float64()))
+			}
+			return 1
+		},
+		"player": func(L *lua.LState) int {
+			L.Push(luaTable(L, map[string]any{
+				"x": g.PlayerPos.X, "y": g.PlayerPos.Y,
+				"hp": g.PlayerHP, "max_hp": g.MaxHP, "soul": g.SoulHP,
+				"damage": g.ShotDamage, "damage_mult": g.damageMult, "fire_delay": g.ShotCooldownBase,
+				"speed": g.MoveSpeed, "crit": g.CritChance, "luck": g.luck,
+				"coins": g.Coins, "bombs": g.Bombs, "keys": g.Keys,
+				"floor": g.Floor, "room": g.CurrentRoomID + 1,
+			}))
+			return 1
+		},
+		"set_stat": func(L *lua.LState) int {
+			v := float64(L.CheckNumber(2))
+			switch name := L.CheckString(1); name {
+			case "damage":
+				g.ShotDamage = maxInt(1, int(v))
+			case "damage_mult":
+				g.damageMult = Clamp(v, 0.1, 10)
+			case "fire_delay":
+				g.ShotCooldownBase = maxInt(2, int(v))
+			case "speed":
+				g.MoveSpeed = Clamp(v, 0.5, 8)
+			case "crit":
+				g.CritChance = Clamp(v, 0, 0.9)
+			case "luck":
+				g.luck = Clamp(v, 0, 0.6)
+			case "max_hp":
+				g.MaxHP = maxInt(2, minInt(maxHearts-g.SoulHP, int(v)))
+				g.PlayerHP = minInt(g.PlayerHP, g.MaxHP)
+			case "hp":
+				g.PlayerHP = maxInt(1, minInt(g.MaxHP, int(v)))
+			default:
+				L.ArgError(1, "unknown stat "+name)
+			}
+			return 0
+		},
+		"give": func(L *lua.LState) int {
+			n := L.OptInt(2, 1)
+			switch what := L.CheckString(1); what {
+			case "coins":
+				g.Coins = maxInt(0, g.Coins+n)
+			case "bombs":
+				g.Bombs = minInt(bombCap, maxInt(0, g.Bombs+n))
+			case "keys":
+				g.Keys = maxInt(0, g.Keys+n)
+			default:
+				L.ArgError(1, "unknown pickup "+what)
+			}
+			return 0
+		},
+		"spawn": func(L *lua.LState) int {
+			kind := L.CheckString(1)
+			pos := Vec2{X: float64(L.CheckNumber(2)), Y: float64(L.CheckNumber(3))}
+			if !strings.Contains(kind, ":") && g.modEnemyByID(prefix+kind) != nil {
+				kind = prefix + kind
+			}
+			e, ok := g.enemyByName(kind, pos)
+			if ok {
+				g.modSpawns = append(g.modSpawns, e)
+			}
+			L.Push(lua.LBool(ok))
+			return 1
+		},
+		"shoot": func(L *lua.LState) int {
+			pos := Vec2{X: float64(L.CheckNumber(1)), Y: float64(L.CheckNumber(2))}
+			vel := Vec2{X: float64(L.CheckNumber(3)), Y: float64(L.CheckNumber(4))}
+			g.modBullets = append(g.modBullets, Bullet{Pos: pos, Prev: pos, Vel: vel, Active: true, Scale: 1, Damage: L.OptInt(5, 0)})
+			return 0
+		},
+		"enemy_shot": func(L *lua.LState) int {
+			pos := Vec2{X: float64(L.CheckNumber(1)), Y: float64(L.CheckNumber(2))}
+			vel := Vec2{X: float64(L.CheckNumber(3)), Y: float64(L.CheckNumber(4))}
+			g.modShots = append(g.modShots, EnemyShot{Pos: pos, Prev: pos, Vel: vel, Active: true})
+			return 0
+		},
+		"enemies": func(L *lua.LState) int {
+			list := L.NewTable()
+			for i, e := range g.Enemies {
+				if e.Alive {
+					list.Append(luaTable(L, g.enemyEvent(i, e)))
+				}
+			}
+			L.Push(list)
+			return 1
+		},
+		"damage_enemy": func(L *lua.LState) int {
+			i := L.CheckInt(1) - 1
+			if i >= 0 && i < len(g.Enemies) && g.Enemies[i].Alive {
+				g.hitEnemy(&g.Enemies[i], maxInt(1, L.OptInt(2, 1)))
+			}
+			return 0
+		},
+		"register_item": func(L *lua.LState) int {
+			t := L.CheckTable(1)
+			id := lua.LVAsString(t.RawGetString("id"))
+			if id == "" {
+				L.ArgError(1, "item needs an id")
+			}
+			it := ModItem{ID: prefix + id, Name: lua.LVAsString(t.RawGetString("name")), mod: g.modByID(m.ID)}
+			if it.Name == "" {
+				it.Name = id
+			}
+			it.collect, _ = t.RawGetString("on_collect").(*lua.LFunction)
+			g.modItems = append(g.modItems, it)
+			return 0
+		},
+		"register_enemy": func(L *lua.LState) int {
+			t := L.CheckTable(1)
+			id := lua.LVAsString(t.RawGetString("id"))
+			base, ok := consoleEnemies[lua.LVAsString(t.RawGetString("base"))]
+			if id == "" || !ok || base == EnemyBoss || base == EnemySpawner {
+				L.ArgError(1, "enemy needs an id and a base of chaser, wander, shooter or dasher")
+			}
+			me := ModEnemy{ID: prefix + id, Base: base, HP: maxInt(1, int(lua.LVAsNumber(t.RawGetString("hp")))), Weight: float64(lua.LVAsNumber(t.RawGetString("weight"))), mod: g.modByID(m.ID)}
+			me.Tint = EnemyColor(Enemy{Kind: base})
+			if c, ok := t.RawGetString("color").(*lua.LTable); ok {
+				me.Tint = color.RGBA{R: uint8(lua.LVAsNumber(c.RawGetInt(1))), G: uint8(lua.LVAsNumber(c.RawGetInt(2))), B: uint8(lua.LVAsNumber(c.RawGetInt(3))), A: 255}
+			}
+			me.update, _ = t.RawGetString("update").(*lua.LFunction)
+			g.modEnemies = append(g.modEnemies, me)
+			return 0
+		},
+	}
+}
+
+func (g *Game) modByID(id string) *Mod {
+	for _, m := range g.mods {
+		if m.Manifest.ID == id {
+			return m
+		}
+	}
+	return nil
+}
+
+func (g *Game) modEnemyByID(id string) *ModEnemy {
+	for i := range g.modEnemies {
+		if g.modEnemies[i].ID == id {
+			return &g.modEnemies[i]
+		}
+	}
+	return nil
+}
+
+// enemyByName builds a built-in enemy from its console name or a mod enemy
+// from its full "mod:id" name.
+func (g *Game) enemyByName(name string, pos Vec2) (Enemy, bool) {
+	if kind, ok := consoleEnemies[name]; ok {
+		return g.newEnemyOfKind(kind, pos), true
+	}
+	if me := g.modEnemyByID(name); me != nil {
+		return g.newModEnemy(*me, pos), true
+	}
+	return Enemy{}, false
+}
+
+func (g *Game) newModEnemy(me ModEnemy, pos Vec2) Enemy {
+	e := g.newEnemyOfKind(me.Base, pos)
+	hp := int(float64(me.HP) * g.modeHPScale())
+	e.HP, e.MaxHP = hp, hp
+	e.Champion = ChampionNone
+	e.Mod = me.ID
+	e.Tint = me.Tint
+	return e
+}
+
+// rollModEnemy swaps a rolled enemy for a mod enemy, each with its own
+// weight as the chance. The RNG is only touched when mods add enemies.
+func (g *Game) rollModEnemy(e Enemy) Enemy {
+	for _, me := range g.modEnemies {
+		if me.Weight > 0 && g.rng.Float64() < me.Weight {
+			return g.newModEnemy(me, e.Pos)
+		}
+	}
+	return e
+}
+
+// updateModEnemy lets the script steer a mod enemy after its base behaviour
+// has run. Returning vx, vy sets its velocity for the tick.
+func (g *Game) updateModEnemy(e *Enemy) {
+	me := g.modEnemyByID(e.Mod)
+	if me == nil || me.update == nil || me.mod.Failed {
+		return
+	}
+	ev := g.enemyEvent(g.enemyIndex(e), *e)
+	ev["vx"], ev["vy"] = e.Vel.X, e.Vel.Y
+	ret := g.modCall(me.mod, me.update, luaTable(me.mod.L, ev))
+	if t, ok := ret.(*lua.LTable); ok {
+		vx, okx := t.RawGetString("vx").(lua.LNumber)
+		vy, oky := t.RawGetString("vy").(lua.LNumber)
+		if okx && oky {
+			e.Vel = Vec2{X: float64(vx), Y: float64(vy)}
+		}
+	}
+}
+
+// rollReward picks a room's item, sometimes from the mod item pool.
+func (g *Game) rollReward() Item {
+	it := Item{Pos: Vec2{X: ScreenW / 2, Y: ScreenH / 2}, Kind: ItemType(g.rng.Intn(int(itemTypeCount)))}
+	if len(g.modItems) > 0 && g.rng.Float64() < modItemChance {
+		it.Mod = g.modItems[g.rng.Intn(len(g.modItems))].ID
+	}
+	return it
+}
+
+func (g *Game) applyModItem(id string) {
+	for _, it := range g.modItems {
+		if it.ID != id {
+			continue
+		}
+		g.collectedMods = append(g.collectedMods, id)
+		g.LastItemText = it.Name
+		g.ItemTextTicks = itemTextDuration
+		if it.collect != nil {
+			g.modCall(it.mod, it.collect)
+		}
+		g.modHook("on_pickup", map[string]any{"kind": "item", "name": it.Name, "mod_item": id})
+		return
+	}
+}
+
+// flushModSpawns brings in whatever scripts spawned during the tick.
+func (g *Game) flushModSpawns() {
+	for _, e := range g.modSpawns {
+		g.Enemies = append(g.Enemies, g.readyEnemy(e))
+	}
+	g.Bullets = append(g.Bullets, g.modBullets...)
+	g.EnemyShots = append(g.EnemyShots, g.modShots...)
+	g.modSpawns = g.modSpawns[:0]
+	g.modBullets = g.modBullets[:0]
+	g.modShots = g.modShots[:0]
+}
+
+func (g *Game) modList() []string {
+	names := make([]string, 0, len(g.mods))
+	for _, m := range g.mods {
+		names = append(names, fmt.Sprintf("%s@%s", m.Manifest.ID, m.Manifest.Version))
+	}
+	return names
+}
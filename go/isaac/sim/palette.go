@@ -0,0 +1,259 @@
+package sim
+
+import "image/color"
+
+const (
+	PaletteDefault      = "default"
+	PaletteDeuteranopia = "deuteranopia"
+	PaletteProtanopia   = "protanopia"
+	PaletteTritanopia   = "tritanopia"
+	PaletteHighContrast = "high_contrast"
+
+	MinGameSpeed = 0.5
+	MaxGameSpeed = 1.0
+)
+
+// PaletteNames lists the palettes in the order F9 cycles through them.
+var PaletteNames = []string{PaletteDefault, PaletteDeuteranopia, PaletteProtanopia, PaletteTritanopia, PaletteHighContrast}
+
+// Palette holds the colours that carry gameplay meaning. Anything not in a
+// palette (floor, walls, HUD chrome) keeps its colour under every palette.
+type Palette struct {
+	Enemies map[EnemyType]color.RGBA
+	Minion  color.RGBA
+	Pickups map[PickupType]color.RGBA
+	Offers  map[OfferType]color.RGBA
+	Doors   map[DoorState]color.RGBA
+	Outline color.RGBA // drawn around enemies and pickups when Width > 0
+	Width   float32
+}
+
+// The colour-blind palettes build on the Okabe-Ito set: every pair of roles
+// that matters differs in lightness as well as hue for that vision type.
+var palettes = map[string]Palette{
+	PaletteDefault: {
+		Enemies: map[EnemyType]color.RGBA{
+			EnemyChaser:  {R: 170, G: 70, B: 70, A: 255},
+			EnemyWander:  {R: 190, G: 120, B: 70, A: 255},
+			EnemyShooter: {R: 145, G: 95, B: 170, A: 255},
+			EnemyDasher:  {R: 205, G: 85, B: 130, A: 255},
+			EnemyBoss:    {R: 145, G: 42, B: 42, A: 255},
+			EnemySpawner: {R: 120, G: 80, B: 70, A: 255},
+		},
+		Minion: color.RGBA{R: 205, G: 120, B: 115, A: 255},
+		Pickups: map[PickupType]color.RGBA{
+			PickupHeart:          {R: 220, G: 145, B: 160, A: 255},
+			PickupSoulHeart:      {R: 140, G: 170, B: 230, A: 255},
+			PickupHeartContainer: {R: 200, G: 40, B: 50, A: 255},
+			PickupBomb:           {R: 125, G: 115, B: 100, A: 255},
+			PickupGoldenBomb:     {R: 235, G: 200, B: 70, A: 255},
+			PickupCoin:           {R: 232, G: 194, B: 92, A: 255},
+			PickupNickel:         {R: 190, G: 195, B: 200, A: 255},
+			PickupDime:           {R: 210, G: 220, B: 235, A: 255},
+			PickupKey:            {R: 210, G: 180, B: 120, A: 255},
+			PickupGoldenKey:      {R: 250, G: 215, B: 60, A: 255},
+			PickupBattery:        {R: 90, G: 200, B: 110, A: 255},
+			PickupTrinket:        {R: 175, G: 140, B: 210, A: 255},
+		},
+		Offers: map[OfferType]color.RGBA{
+			OfferHeart:     {R: 220, G: 125, B: 145, A: 255},
+			OfferBombPack:  {R: 125, G: 125, B: 120, A: 255},
+			OfferKey:       {R: 210, G: 180, B: 120, A: 255},
+			OfferSoulHeart: {R: 140, G: 170, B: 230, A: 255},
+		},
+		Doors: map[DoorState]color.RGBA{
+			DoorOpen:   {R: 130, G: 140, B: 95, A: 255},
+			DoorClosed: {R: 120, G: 96, B: 80, A: 255},
+			DoorLocked: {R: 215, G: 180, B: 85, A: 255},
+			DoorBoss:   {R: 110, G: 30, B: 30, A: 255},
+		},
+	},
+	PaletteDeuteranopia: {
+		Enemies: map[EnemyType]color.RGBA{
+			EnemyChaser:  {R: 213, G: 94, B: 0, A: 255},
+			EnemyWander:  {R: 240, G: 228, B: 66, A: 255},
+			EnemyShooter: {R: 0, G: 114, B: 178, A: 255},
+			EnemyDasher:  {R: 86, G: 180, B: 233, A: 255},
+			EnemyBoss:    {R: 204, G: 121, B: 167, A: 255},
+			EnemySpawner: {R: 120, G: 110, B: 100, A: 255},
+		},
+		Minion: color.RGBA{R: 230, G: 159, B: 0, A: 255},
+		Pickups: map[PickupType]color.RGBA{
+			PickupHeart:          {R: 230, G: 159, B: 0, A: 255},
+			PickupSoulHeart:      {R: 86, G: 180, B: 233, A: 255},
+			PickupHeartContainer: {R: 213, G: 94, B: 0, A: 255},
+			PickupBomb:           {R: 60, G: 60, B: 60, A: 255},
+			PickupGoldenBomb:     {R: 240, G: 228, B: 66, A: 255},
+			PickupCoin:           {R: 240, G: 228, B: 66, A: 255},
+			PickupNickel:         {R: 200, G: 200, B: 200, A: 255},
+			PickupDime:           {R: 255, G: 255, B: 255, A: 255},
+			PickupKey:            {R: 0, G: 114, B: 178, A: 255},
+			PickupGoldenKey:      {R: 240, G: 228, B: 66, A: 255},
+			PickupBattery:        {R: 0, G: 158, B: 115, A: 255},
+			PickupTrinket:        {R: 204, G: 121, B: 167, A: 255},
+		},
+		Offers: map[OfferType]color.RGBA{
+			OfferHeart:     {R: 230, G: 159, B: 0, A: 255},
+			OfferBombPack:  {R: 60, G: 60, B: 60, A: 255},
+			OfferKey:       {R: 0, G: 114, B: 178, A: 255},
+			OfferSoulHeart: {R: 86, G: 180, B: 233, A: 255},
+		},
+		Doors: map[DoorState]color.RGBA{
+			DoorOpen:   {R: 86, G: 180, B: 233, A: 255},
+			DoorClosed: {R: 70, G: 60, B: 55, A: 255},
+			DoorLocked: {R: 240, G: 228, B: 66, A: 255},
+			DoorBoss:   {R: 213, G: 94, B: 0, A: 255},
+		},
+		Outline: color.RGBA{R: 20, G: 16, B: 16, A: 255},
+		Width:   1.5,
+	},
+	PaletteProtanopia: {
+		Enemies: map[EnemyType]color.RGBA{
+			EnemyChaser:  {R: 230, G: 159, B: 0, A: 255},
+			EnemyWander:  {R: 240, G: 228, B: 66, A: 255},
+			EnemyShooter: {R: 0, G: 114, B: 178, A: 255},
+			EnemyDasher:  {R: 86, G: 180, B: 233, A: 255},
+			EnemyBoss:    {R: 204, G: 121, B: 167, A: 255},
+			EnemySpawner: {R: 120, G: 110, B: 100, A: 255},
+		},
+		Minion: color.RGBA{R: 250, G: 200, B: 120, A: 255},
+		Pickups: map[PickupType]color.RGBA{
+			PickupHeart:          {R: 230, G: 159, B: 0, A: 255},
+			PickupSoulHeart:      {R: 86, G: 180, B: 233, A: 255},
+			PickupHeartContainer: {R: 250, G: 200, B: 120, A: 255},
+			PickupBomb:           {R: 60, G: 60, B: 60, A: 255},
+			PickupGoldenBomb:     {R: 240, G: 228, B: 66, A: 255},
+			PickupCoin:           {R: 240, G: 228, B: 66, A: 255},
+			PickupNickel:         {R: 200, G: 200, B: 200, A: 255},
+			PickupDime:           {R: 255, G: 255, B: 255, A: 255},
+			PickupKey:            {R: 0, G: 114, B: 178, A: 255},
+			PickupGoldenKey:      {R: 240, G: 228, B: 66, A: 255},
+			PickupBattery:        {R: 0, G: 158, B: 115, A: 255},
+			PickupTrinket:        {R: 204, G: 121, B: 167, A: 255},
+		},
+		Offers: map[OfferType]color.RGBA{
+			OfferHeart:     {R: 230, G: 159, B: 0, A: 255},
+			OfferBombPack:  {R: 60, G: 60, B: 60, A: 255},
+			OfferKey:       {R: 0, G: 114, B: 178, A: 255},
+			OfferSoulHeart: {R: 86, G: 180, B: 233, A: 255},
+		},
+		Doors: map[DoorState]color.RGBA{
+			DoorOpen:   {R: 86, G: 180, B: 233, A: 255},
+			DoorClosed: {R: 70, G: 60, B: 55, A: 255},
+			DoorLocked: {R: 240, G: 228, B: 66, A: 255},
+			DoorBoss:   {R: 230, G: 159, B: 0, A: 255},
+		},
+		Outline: color.RGBA{R: 20, G: 16, B: 16, A: 255},
+		Width:   1.5,
+	},
+	PaletteTritanopia: {
+		Enemies: map[EnemyType]color.RGBA{
+			EnemyChaser:  {R: 220, G: 50, B: 50, A: 255},
+			EnemyWander:  {R: 0, G: 160, B: 160, A: 255},
+			EnemyShooter: {R: 255, G: 140, B: 190, A: 255},
+			EnemyDasher:  {R: 235, G: 235, B: 235, A: 255},
+			EnemyBoss:    {R: 150, G: 0, B: 40, A: 255},
+			EnemySpawner: {R: 110, G: 110, B: 110, A: 255},
+		},
+		Minion: color.RGBA{R: 255, G: 170, B: 170, A: 255},
+		Pickups: map[PickupType]color.RGBA{
+			PickupHeart:          {R: 220, G: 50, B: 50, A: 255},
+			PickupSoulHeart:      {R: 0, G: 160, B: 160, A: 255},
+			PickupHeartContainer: {R: 150, G: 0, B: 40, A: 255},
+			PickupBomb:           {R: 50, G: 50, B: 50, A: 255},
+			PickupGoldenBomb:     {R: 255, G: 140, B: 190, A: 255},
+			PickupCoin:           {R: 255, G: 140, B: 190, A: 255},
+			PickupNickel:         {R: 190, G: 190, B: 190, A: 255},
+			PickupDime:           {R: 255, G: 255, B: 255, A: 255},
+			PickupKey:            {R: 0, G: 110, B: 110, A: 255},
+			PickupGoldenKey:      {R: 255, G: 140, B: 190, A: 255},
+			PickupBattery:        {R: 0, G: 200, B: 200, A: 255},
+			PickupTrinket:        {R: 230, G: 60, B: 60, A: 255},
+		},
+		Offers: map[OfferType]color.RGBA{
+			OfferHeart:     {R: 220, G: 50, B: 50, A: 255},
+			OfferBombPack:  {R: 50, G: 50, B: 50, A: 255},
+			OfferKey:       {R: 0, G: 110, B: 110, A: 255},
+			OfferSoulHeart: {R: 0, G: 160, B: 160, A: 255},
+		},
+		Doors: map[DoorState]color.RGBA{
+			DoorOpen:   {R: 0, G: 160, B: 160, A: 255},
+			DoorClosed: {R: 70, G: 60, B: 55, A: 255},
+			DoorLocked: {R: 255, G: 140, B: 190, A: 255},
+			DoorBoss:   {R: 220, G: 50, B: 50, A: 255},
+		},
+		Outline: color.RGBA{R: 20, G: 16, B: 16, A: 255},
+		Width:   1.5,
+	},
+	PaletteHighContrast: {
+		Enemies: map[EnemyType]color.RGBA{
+			EnemyChaser:  {R: 255, G: 60, B: 60, A: 255},
+			EnemyWander:  {R: 255, G: 200, B: 0, A: 255},
+			EnemyShooter: {R: 0, G: 200, B: 255, A: 255},
+			EnemyDasher:  {R: 255, G: 255, B: 255, A: 255},
+			EnemyBoss:    {R: 255, G: 0, B: 255, A: 255},
+			EnemySpawner: {R: 150, G: 150, B: 150, A: 255},
+		},
+		Minion: color.RGBA{R: 255, G: 150, B: 150, A: 255},
+		Pickups: map[PickupType]color.RGBA{
+			PickupHeart:          {R: 255, G: 60, B: 60, A: 255},
+			PickupSoulHeart:      {R: 0, G: 200, B: 255, A: 255},
+			PickupHeartContainer: {R: 255, G: 0, B: 255, A: 255},
+			PickupBomb:           {R: 0, G: 0, B: 0, A: 255},
+			PickupGoldenBomb:     {R: 255, G: 200, B: 0, A: 255},
+			PickupCoin:           {R: 255, G: 230, B: 0, A: 255},
+			PickupNickel:         {R: 200, G: 200, B: 200, A: 255},
+			PickupDime:           {R: 255, G: 255, B: 255, A: 255},
+			PickupKey:            {R: 0, G: 255, B: 120, A: 255},
+			PickupGoldenKey:      {R: 255, G: 200, B: 0, A: 255},
+			PickupBattery:        {R: 0, G: 255, B: 120, A: 255},
+			PickupTrinket:        {R: 255, G: 0, B: 255, A: 255},
+		},
+		Offers: map[OfferType]color.RGBA{
+			OfferHeart:     {R: 255, G: 60, B: 60, A: 255},
+			OfferBombPack:  {R: 0, G: 0, B: 0, A: 255},
+			OfferKey:       {R: 0, G: 255, B: 120, A: 255},
+			OfferSoulHeart: {R: 0, G: 200, B: 255, A: 255},
+		},
+		Doors: map[DoorState]color.RGBA{
+			DoorOpen:   {R: 0, G: 255, B: 120, A: 255},
+			DoorClosed: {R: 0, G: 0, B: 0, A: 255},
+			DoorLocked: {R: 255, G: 230, B: 0, A: 255},
+			DoorBoss:   {R: 255, G: 0, B: 255, A: 255},
+		},
+		Outline: color.RGBA{R: 255, G: 255, B: 255, A: 255},
+		Width:   2,
+	},
+}
+
+// palette is the active palette. It is package state because the drawing
+// helpers of every frontend read it, as do the particle bursts.
+var palette = palettes[PaletteDefault]
+
+func ApplyPalette(name string) {
+	if p, ok := palettes[name]; ok {
+		palette = p
+	}
+}
+
+func CurrentPalette() Palette { return palette }
+
+var championTints = map[ChampionType]color.RGBA{
+	ChampionSplit: {R: 120, G: 205, B: 90, A: 255},
+	ChampionRapid: {R: 240, G: 205, B: 70, A: 255},
+	ChampionSwift: {R: 90, G: 175, B: 235, A: 255},
+}
+
+// EnemyColor is the fill of an enemy under the active palette.
+func EnemyColor(e Enemy) color.RGBA {
+	if tint, ok := championTints[e.Champion]; ok {
+		return tint
+	}
+	if e.Minion {
+		return palette.Minion
+	}
+	if e.Mod != "" {
+		return e.Tint
+	}
+	return palette.Enemies[e.Kind]
+}
@@ -0,0 +1,191 @@
+package sim
+
+const (
+	bombCap     = 9
+	maxHearts   = 24 // red and soul HP together, in half hearts
+	nickelValue = 5
+	dimeValue   = 10
+)
+
+// dropEntry is one row of a weighted drop table. Its base weight comes from
+// the tuning by Name; luck adds LuckWeight per point, capped at MaxWeight
+// when that is set. A row with no Count and no Score is the "nothing drops"
+// row.
+type dropEntry struct {
+	Name       string
+	Kind       PickupType
+	Count      int
+	Score      int
+	LuckWeight float64
+	MaxWeight  float64
+	Text       string
+}
+
+type dropTable []dropEntry
+
+func (e dropEntry) empty() bool { return e.Count == 0 && e.Score == 0 }
+
+// Enemy weights are probabilities out of 1, so the empty row is what is left
+// once everything else has been given its share.
+var enemyDrops = dropTable{
+	{Name: "heart", Kind: PickupHeart, Count: 1, LuckWeight: 0.30, MaxWeight: 0.40},
+	{Name: "soul_heart", Kind: PickupSoulHeart, Count: 1, LuckWeight: 0.05, MaxWeight: 0.06},
+	{Name: "bomb", Kind: PickupBomb, Count: 1, LuckWeight: 0.18, MaxWeight: 0.28},
+	{Name: "golden_bomb", Kind: PickupGoldenBomb, Count: 1},
+	{Name: "coin", Kind: PickupCoin, Count: 1, LuckWeight: 0.20, MaxWeight: 0.60},
+	{Name: "nickel", Kind: PickupNickel, Count: 1, LuckWeight: 0.04, MaxWeight: 0.08},
+	{Name: "dime", Kind: PickupDime, Count: 1, LuckWeight: 0.02, MaxWeight: 0.03},
+	{Name: "key", Kind: PickupKey, Count: 1, LuckWeight: 0.15, MaxWeight: 0.24},
+	{Name: "golden_key", Kind: PickupGoldenKey, Count: 1},
+	{Name: "battery", Kind: PickupBattery, Count: 1, LuckWeight: 0.02, MaxWeight: 0.03},
+	{Name: "trinket", Kind: PickupTrinket, Count: 1, LuckWeight: 0.004, MaxWeight: 0.01},
+	{}, // weighted by Tuning.NoDropWeight
+}
+
+var chestDrops = dropTable{
+	{Name: "coins", Kind: PickupCoin, Count: 2, Text: "chest.coins"},
+	{Name: "nickel", Kind: PickupNickel, Count: 1, Text: "chest.nickel"},
+	{Name: "bomb", Kind: PickupBomb, Count: 1, Text: "chest.bomb"},
+	{Name: "golden_bomb", Kind: PickupGoldenBomb, Count: 1, Text: "chest.golden_bomb"},
+	{Name: "heart", Kind: PickupHeart, Count: 1, Text: "chest.heart"},
+	{Name: "soul_heart", Kind: PickupSoulHeart, Count: 1, Text: "chest.soul_heart"},
+	{Name: "heart_container", Kind: PickupHeartContainer, Count: 1, Text: "chest.heart_container"},
+	{Name: "key", Kind: PickupKey, Count: 1, Text: "chest.key"},
+	{Name: "golden_key", Kind: PickupGoldenKey, Count: 1, Text: "chest.golden_key"},
+	{Name: "battery", Kind: PickupBattery, Count: 1, Text: "chest.battery"},
+	{Name: "trinket", Kind: PickupTrinket, Count: 1, Text: "chest.trinket"},
+	{Name: "score", Score: 40, Text: "chest.score"},
+}
+
+func (g *Game) dropWeight(e dropEntry, weights map[string]float64) float64 {
+	if e.empty() {
+		return g.Tune.NoDropWeight
+	}
+	base := weights[e.Name]
+	w := base + g.luck*e.LuckWeight
+	if e.MaxWeight > 0 && w > e.MaxWeight {
+		w = max(e.MaxWeight, base)
+	}
+	return w
+}
+
+// rollDrop picks a row by weight. A guaranteed roll skips the empty rows.
+func (g *Game) rollDrop(t dropTable, weights map[string]float64, guaranteed bool) dropEntry {
+	total := 0.0
+	for _, e := range t {
+		if guaranteed && e.empty() {
+			continue
+		}
+		total += g.dropWeight(e, weights)
+	}
+	r := g.rng.Float64() * total
+	for _, e := range t {
+		if guaranteed && e.empty() {
+			continue
+		}
+		r -= g.dropWeight(e, weights)
+		if r < 0 {
+			return e
+		}
+	}
+	return dropEntry{}
+}
+
+// spawnDrop places a rolled row at pos, spreading multiples sideways.
+func (g *Game) spawnDrop(e dropEntry, pos Vec2) {
+	for i := 0; i < e.Count; i++ {
+		p := Vec2{X: pos.X + float64(i)*16, Y: pos.Y}
+		pk := Pickup{Pos: p, Kind: e.Kind, Active: true}
+		if e.Kind == PickupTrinket {
+			pk.Trinket = g.rollTrinket()
+		}
+		g.Pickups = append(g.Pickups, pk)
+	}
+	g.Score += e.Score
+}
+
+func (g *Game) dropLoot(pos Vec2, guaranteed bool) {
+	g.spawnDrop(g.rollDrop(enemyDrops, g.Tune.EnemyDrops, guaranteed), pos)
+}
+
+var pickupNames = map[PickupType]string{
+	PickupHeart:          "heart",
+	PickupBomb:           "bomb",
+	PickupCoin:           "coin",
+	PickupKey:            "key",
+	PickupSoulHeart:      "soul_heart",
+	PickupHeartContainer: "heart_container",
+	PickupNickel:         "nickel",
+	PickupDime:           "dime",
+	PickupGoldenKey:      "golden_key",
+	PickupGoldenBomb:     "golden_bomb",
+	PickupBattery:        "battery",
+	PickupTrinket:        "trinket",
+}
+
+func (g *Game) collectPickup(p Pickup) {
+	switch p.Kind {
+	case PickupHeart:
+		g.PlayerHP = minInt(g.MaxHP, g.PlayerHP+1)
+		g.LastItemText = g.Tr("pickup.heart")
+	case PickupSoulHeart:
+		g.SoulHP = minInt(maxHearts-g.MaxHP, g.SoulHP+2)
+		g.LastItemText = g.Tr("pickup.soul_heart")
+	case PickupHeartContainer:
+		g.MaxHP = minInt(maxHearts-g.SoulHP, g.MaxHP+2)
+		g.PlayerHP = minInt(g.MaxHP, g.PlayerHP+2)
+		g.LastItemText = g.Tr("pickup.heart_container")
+	case PickupBomb:
+		g.Bombs = minInt(bombCap, g.Bombs+1)
+		g.LastItemText = g.Tr("pickup.bomb")
+	case PickupGoldenBomb:
+		g.GoldenBomb = true
+		g.LastItemText = g.Tr("pickup.golden_bomb")
+	case PickupCoin:
+		g.Coins++
+		g.LastItemText = g.Tr("pickup.coin")
+		g.onTrinketCoin()
+	case PickupNickel:
+		g.Coins += nickelValue
+		g.LastItemText = g.Tr("pickup.nickel")
+		g.onTrinketCoin()
+	case PickupDime:
+		g.Coins += dimeValue
+		g.LastItemText = g.Tr("pickup.dime")
+		g.onTrinketCoin()
+	case PickupKey:
+		g.Keys++
+		g.LastItemText = g.Tr("pickup.key")
+	case PickupGoldenKey:
+		g.GoldenKey = true
+		g.LastItemText = g.Tr("pickup.golden_key")
+	case PickupBattery:
+		g.ActiveCharge = ActiveDefs[g.ActiveItem].Charges
+		g.LastItemText = g.Tr("pickup.battery")
+	case PickupTrinket:
+		g.takeTrinket(p.Trinket)
+	}
+	g.ItemTextTicks = itemTextDuration
+	g.emitEvent("pickup")
+	g.modHook("on_pickup", map[string]any{"kind": "pickup", "name": pickupNames[p.Kind]})
+}
+
+// spendKey uses up a key, or none at all with the golden key.
+func (g *Game) spendKey() bool {
+	if g.GoldenKey {
+		return true
+	}
+	if g.Keys <= 0 {
+		return false
+	}
+	g.Keys--
+	return true
+}
+
+func (g *Game) hasBomb() bool { return g.GoldenBomb || g.Bombs > 0 }
+
+func (g *Game) spendBomb() {
+	if !g.GoldenBomb {
+		g.Bombs--
+	}
+}
@@ -0,0 +1,115 @@
+package sim
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Replay is a run's seed and setup plus the input of every tick, enough to
+// play the run again tick for tick. It assumes the same mods and tuning.
+// Console commands are not recorded, so a Cheated replay will not play back
+// the same.
+type Replay struct {
+	Seed         int64         `json:"seed"`
+	Cheated      bool          `json:"cheated,omitempty"`
+	Character    string        `json:"character"`
+	Mode         string        `json:"mode"`
+	AimAssist    float64       `json:"aim_assist"`
+	TearVelocity string        `json:"tear_velocity"`
+	Frames       []ReplayFrame `json:"frames"`
+}
+
+// ReplayFrame is one input held for Ticks ticks in a row. A Reset frame is
+// the restart after a death and has no input.
+type ReplayFrame struct {
+	In    TickInput `json:"in"`
+	Ticks int       `json:"ticks,omitempty"`
+	Reset bool      `json:"reset,omitempty"`
+}
+
+func (g *Game) beginReplay() {
+	g.replay = Replay{
+		Seed:         g.RunSeed,
+		Character:    g.CharacterID,
+		Mode:         g.Mode.String(),
+		AimAssist:    g.Settings.AimAssist,
+		TearVelocity: g.Settings.TearVelocity,
+	}
+}
+
+func (g *Game) recordTick(in TickInput) {
+	if g.Playback {
+		return
+	}
+	if n := len(g.replay.Frames); n > 0 && !g.replay.Frames[n-1].Reset && g.replay.Frames[n-1].In == in {
+		g.replay.Frames[n-1].Ticks++
+		return
+	}
+	g.replay.Frames = append(g.replay.Frames, ReplayFrame{In: in, Ticks: 1})
+}
+
+func (g *Game) recordReset() {
+	if g.Playback || len(g.replay.Frames) == 0 {
+		return
+	}
+	g.replay.Frames = append(g.replay.Frames, ReplayFrame{Reset: true})
+}
+
+// Replay returns the current run's recording so far.
+func (g *Game) Replay() Replay {
+	r := g.replay
+	r.Cheated = g.Cheated
+	r.Frames = append([]ReplayFrame(nil), r.Frames...)
+	return r
+}
+
+// Ticks is how many simulation steps the replay covers.
+func (r Replay) Ticks() int {
+	n := 0
+	for _, f := range r.Frames {
+		n += f.Ticks
+	}
+	return n
+}
+
+func (r Replay) Save(path string) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func LoadReplay(path string) (Replay, error) {
+	var r Replay
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+	err = json.Unmarshal(data, &r)
+	return r, err
+}
+
+// PlayReplay restarts the game with the replay's setup and steps through all
+// of its input, calling tick after every step. Nothing is saved meanwhile.
+func (g *Game) PlayReplay(r Replay, tick func()) {
+	g.Playback = true
+	defer func() { g.Playback = false }()
+	g.CharacterID = r.Character
+	g.Mode = runModeByName(r.Mode)
+	g.Settings.AimAssist = r.AimAssist
+	g.Settings.TearVelocity = r.TearVelocity
+	g.StartRunWithSeed(r.Seed)
+	for _, f := range r.Frames {
+		if f.Reset {
+			g.ResetRun()
+			continue
+		}
+		for i := 0; i < f.Ticks; i++ {
+			g.Step(f.In)
+			if tick != nil {
+				tick()
+			}
+		}
+	}
+}
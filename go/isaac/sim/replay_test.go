@@ -0,0 +1,53 @@
+package sim
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestReplayRoundTrip plays scripted input with a death restart in the
+// middle, saves the replay and checks that playing it back in a fresh game
+// ends in the same state.
+func TestReplayRoundTrip(t *testing.T) {
+	dir := inTempDir(t)
+	g := NewGame()
+	g.StartRunWithSeed(7)
+	dirs := []Vec2{{X: 1}, {Y: 1}, {X: -1}, {Y: -1}}
+	for i := 0; i < 900; i++ {
+		if i == 450 {
+			g.ResetRun()
+		}
+		g.Step(TickInput{Move: dirs[i/40%4], Aim: dirs[i/25%4], Bomb: i%200 == 0})
+	}
+	path := filepath.Join(dir, "replay.json")
+	if err := g.Replay().Save(path); err != nil {
+		t.Fatal(err)
+	}
+	r, err := LoadReplay(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Ticks() != 900 {
+		t.Fatalf("replay covers %d ticks, want 900", r.Ticks())
+	}
+
+	h := NewGame()
+	steps := 0
+	h.PlayReplay(r, func() { steps++ })
+	if steps != 900 {
+		t.Fatalf("playback ran %d steps, want 900", steps)
+	}
+	type state struct {
+		Pos                    Vec2
+		HP, Score, Room, Bombs int
+		Enemies                []Enemy
+		Bullets                []Bullet
+	}
+	snap := func(g *Game) state {
+		return state{g.PlayerPos, g.PlayerHP, g.Score, g.CurrentRoomID, g.Bombs, g.Enemies, g.Bullets}
+	}
+	if a, b := snap(g), snap(h); !reflect.DeepEqual(a, b) {
+		t.Errorf("playback diverged:\n got %+v\nwant %+v", b, a)
+	}
+}
@@ -0,0 +1,276 @@
+package sim
+
+import "image/color"
+
+const (
+	shopSlots        = 5
+	shopSaleChance   = 0.25
+	ShopkeeperRadius = 16
+	keeperMarkup     = 2
+	keeperMinions    = 3
+)
+
+var ShopkeeperPos = Vec2{X: ScreenW / 2, Y: 150}
+
+type Rarity int
+
+const (
+	RarityCommon Rarity = iota
+	RarityUncommon
+	RarityRare
+)
+
+var ItemRarity = map[ItemType]Rarity{
+	ItemHeal:       RarityCommon,
+	ItemSpeed:      RarityCommon,
+	ItemLuck:       RarityCommon,
+	ItemCompass:    RarityCommon,
+	ItemFireRate:   RarityUncommon,
+	ItemCrit:       RarityUncommon,
+	ItemBombMaster: RarityUncommon,
+	ItemShield:     RarityUncommon,
+	ItemDamage:     RarityRare,
+	ItemPierce:     RarityRare,
+	ItemMultiShot:  RarityRare,
+	ItemLaser:      RarityRare,
+	ItemChargeShot: RarityUncommon,
+	ItemHoming:     RarityUncommon,
+	ItemBoomerang:  RarityCommon,
+	ItemExplosive:  RarityRare,
+	ItemBuddy:      RarityUncommon,
+	ItemSentry:     RarityRare,
+	ItemHalo:       RarityUncommon,
+	ItemMagnet:     RarityCommon,
+}
+
+var rarityPrices = map[Rarity]int{RarityCommon: 6, RarityUncommon: 10, RarityRare: 15}
+
+var offerNames = map[OfferType]string{
+	OfferHeart:     "Heart Bundle",
+	OfferBombPack:  "Bomb Pack",
+	OfferKey:       "Key Pack",
+	OfferSoulHeart: "Soul Heart",
+}
+
+type PurchaseRecord struct {
+	Floor int    `json:"floor"`
+	Offer string `json:"offer"`
+	Price int    `json:"price"`
+	Sale  bool   `json:"sale,omitempty"`
+	Free  bool   `json:"free,omitempty"`
+}
+
+// shopItemSlots grows the item stock with depth: one item on the first floor,
+// up to three from floor 4.
+func (g *Game) shopItemSlots() int { return minInt(3, 1+g.Floor/2) }
+
+// rollRarity favours common items early and shifts towards rare ones as the
+// floors go by.
+func (g *Game) rollRarity() Rarity {
+	depth := float64(g.Floor - 1)
+	weights := []float64{
+		RarityCommon:   Clamp(70-8*depth, 30, 70),
+		RarityUncommon: 25 + 3*depth,
+		RarityRare:     5 + 5*depth,
+	}
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	r := g.rng.Float64() * total
+	for i, w := range weights {
+		r -= w
+		if r < 0 {
+			return Rarity(i)
+		}
+	}
+	return RarityCommon
+}
+
+// rollShopItem picks an item of the rolled rarity that the shop is not
+// already selling.
+func (g *Game) rollShopItem(taken map[ItemType]bool) ItemType {
+	rarity := g.rollRarity()
+	pool := make([]ItemType, 0, len(ItemRarity))
+	for it := ItemDamage; it < itemTypeCount; it++ {
+		if ItemRarity[it] == rarity && !taken[it] {
+			pool = append(pool, it)
+		}
+	}
+	if len(pool) == 0 {
+		for it := ItemDamage; it < itemTypeCount; it++ {
+			if !taken[it] {
+				pool = append(pool, it)
+			}
+		}
+	}
+	return pool[g.rng.Intn(len(pool))]
+}
+
+func (g *Game) rollConsumable() (OfferType, int) {
+	switch g.rng.Intn(4) {
+	case 0:
+		return OfferHeart, 3 + g.rng.Intn(2)
+	case 1:
+		return OfferBombPack, 5 + g.rng.Intn(2)
+	case 2:
+		return OfferKey, 5 + g.rng.Intn(2)
+	default:
+		return OfferSoulHeart, 6 + g.rng.Intn(2)
+	}
+}
+
+// rollShopStock fills the shelves left to right: items first, consumables in
+// the remaining slots.
+func (g *Game) rollShopStock(n int) []ShopOffer {
+	offers := make([]ShopOffer, 0, n)
+	taken := map[ItemType]bool{}
+	items := g.shopItemSlots()
+	for i := 0; i < n; i++ {
+		if i < items {
+			it := g.rollShopItem(taken)
+			taken[it] = true
+			offers = append(offers, ShopOffer{Kind: OfferItem, Item: it, Price: rarityPrices[ItemRarity[it]]})
+			continue
+		}
+		kind, price := g.rollConsumable()
+		offers = append(offers, ShopOffer{Kind: kind, Price: price})
+	}
+	return offers
+}
+
+func (g *Game) freeItemChance() float64 { return Clamp(0.5*g.luck, 0, 0.25) }
+
+func (g *Game) populateShopRoom(r *Room) {
+	r.Reward = Item{Taken: true}
+	r.Keeper = true
+	r.Offers = g.rollShopStock(shopSlots)
+	sale := g.rng.Float64() < shopSaleChance
+	for i := range r.Offers {
+		r.Offers[i].Pos = Vec2{X: 300 + float64(i)*90, Y: 240}
+		r.Offers[i].Sale = sale
+	}
+	if g.rng.Float64() < g.freeItemChance() {
+		r.Offers[g.rng.Intn(g.shopItemSlots())].Free = true
+	}
+}
+
+// OfferPrice is what the player actually pays: sales halve the base price,
+// Cain's bargain applies next and every dead shopkeeper adds a markup.
+func (g *Game) OfferPrice(o ShopOffer) int {
+	if o.Free {
+		return 0
+	}
+	p := o.Price
+	if o.Sale {
+		p = maxInt(1, (p+1)/2)
+	}
+	return g.shopPrice(p) + keeperMarkup*g.keepersKilled
+}
+
+func offerName(o ShopOffer) string {
+	if o.Kind == OfferItem {
+		return itemNames[o.Item]
+	}
+	return offerNames[o.Kind]
+}
+
+func (g *Game) tryBuyShopOffer() {
+	if g.CurrentRoom().Type != RoomShop || !g.input.Buy {
+		return
+	}
+	for i := range g.Offers {
+		o := &g.Offers[i]
+		if o.Purchased || Distance(g.PlayerPos, o.Pos) > shopInteractRadius {
+			continue
+		}
+		price := g.OfferPrice(*o)
+		if g.Coins < price {
+			g.StatusText = g.Tr("shop.no_coins")
+			g.StatusTextTick = 90
+			return
+		}
+		g.Coins -= price
+		o.Purchased = true
+		switch o.Kind {
+		case OfferHeart:
+			g.PlayerHP = minInt(g.MaxHP, g.PlayerHP+2)
+			g.LastItemText = g.Tr("shop.bought_hearts", 2)
+		case OfferBombPack:
+			g.Bombs = minInt(bombCap, g.Bombs+3)
+			g.LastItemText = g.Trn("shop.bought_bombs", 3)
+		case OfferKey:
+			g.Keys += 2
+			g.LastItemText = g.Trn("shop.bought_keys", 2)
+		case OfferSoulHeart:
+			g.SoulHP = minInt(maxHearts-g.MaxHP, g.SoulHP+2)
+			g.LastItemText = g.Tr("shop.bought_soul_heart")
+		case OfferItem:
+			g.applyItem(o.Item)
+		}
+		g.ItemTextTicks = itemTextDuration
+		g.purchases = append(g.purchases, PurchaseRecord{Floor: g.Floor, Offer: offerName(*o), Price: price, Sale: o.Sale, Free: o.Free})
+		g.emitEvent("shop_buy")
+		g.SaveMeta()
+		return
+	}
+}
+
+// tryRerollShop restocks the unsold shelves. A sale running in the shop
+// carries over; free items do not.
+func (g *Game) tryRerollShop() {
+	if g.CurrentRoom().Type != RoomShop || !g.input.Reroll {
+		return
+	}
+	cost := 2 + g.ShopRerolls
+	if g.Coins < cost {
+		g.StatusText = g.Trn("shop.need_reroll", cost)
+		g.StatusTextTick = 90
+		return
+	}
+	g.Coins -= cost
+	g.ShopRerolls++
+	stock := g.rollShopStock(len(g.Offers))
+	for i := range g.Offers {
+		o := &g.Offers[i]
+		if o.Purchased {
+			continue
+		}
+		next := stock[i]
+		next.Pos, next.Sale = o.Pos, o.Sale
+		*o = next
+	}
+	g.StatusText = g.Tr("shop.rerolled")
+	g.StatusTextTick = 80
+}
+
+func (g *Game) ShopOnSale() bool {
+	for _, o := range g.Offers {
+		if o.Sale && !o.Purchased {
+			return true
+		}
+	}
+	return false
+}
+
+// bombShopkeeper kills the shopkeeper when a blast reaches the counter. The
+// till spills coins, but angry minions pour out and every shop after this one
+// costs more.
+func (g *Game) bombShopkeeper(pos Vec2, radius float64) {
+	room := g.CurrentRoom()
+	if room.Type != RoomShop || !room.Keeper || Distance(pos, ShopkeeperPos) > radius+ShopkeeperRadius {
+		return
+	}
+	room.Keeper = false
+	g.keepersKilled++
+	g.FX.burst(ShopkeeperPos, 18, 3.2, 28, 3, color.RGBA{R: 150, G: 120, B: 95, A: 255})
+	g.addBloodSplat(ShopkeeperPos, true)
+	g.spawnDrop(dropEntry{Kind: PickupCoin, Count: 3}, Vec2{X: ShopkeeperPos.X - 16, Y: ShopkeeperPos.Y})
+	g.spawnDrop(dropEntry{Kind: PickupNickel, Count: 1}, Vec2{X: ShopkeeperPos.X, Y: ShopkeeperPos.Y + 20})
+	for i := 0; i < keeperMinions; i++ {
+		g.Enemies = append(g.Enemies, g.readyEnemy(g.newMinion(ShopkeeperPos)))
+	}
+	g.StatusText = g.Tr("shop.keeper_killed", keeperMarkup)
+	g.StatusTextTick = 120
+	g.emitEvent("shopkeeper_kill")
+}
@@ -0,0 +1,32 @@
+package sim
+
+import "testing"
+
+// TestKeeperMinionsPayNoSecondClear checks that killing the minions of a
+// bombed shopkeeper does not pay the room-clear rewards again.
+func TestKeeperMinionsPayNoSecondClear(t *testing.T) {
+	inTempDir(t)
+	g := NewGame()
+	g.StartRunWithSeed(5)
+	g.swapRoom(g.shopRoomID, Vec2{X: ScreenW / 2, Y: ScreenH - 80})
+	if !g.RoomClear || !g.CurrentRoom().Keeper {
+		t.Fatal("the shop is not clear or has no keeper")
+	}
+	g.ActiveItem, g.ActiveCharge = ActivePotion, 0
+
+	g.bombShopkeeper(ShopkeeperPos, 10)
+	g.updateRoomClear()
+	if g.RoomClear || len(g.Enemies) == 0 {
+		t.Fatal("no minions came out")
+	}
+	for i := range g.Enemies {
+		g.Enemies[i].Alive = false
+	}
+	g.updateRoomClear()
+	if !g.RoomClear {
+		t.Fatal("the shop did not clear again")
+	}
+	if g.ActiveCharge != 0 {
+		t.Errorf("active charge is %d after the minions died, want 0", g.ActiveCharge)
+	}
+}
@@ -0,0 +1,190 @@
+package sim
+
+import "math"
+
+const (
+	spawnerDelayTicks = 150
+	spawnerMinionCap  = 5
+	waveDelayTicks    = 45
+	championHPMult    = 2
+	championSpeedMult = 1.4
+	splitMinions      = 2
+)
+
+type ChampionType int
+
+const (
+	ChampionNone ChampionType = iota
+	ChampionSplit
+	ChampionRapid
+	ChampionSwift
+)
+
+// Spawn chances grow with the floor: floor 1 has rare champions, no spawners
+// and few waves.
+func (g *Game) championChance() float64 { return Clamp(0.03+0.04*float64(g.Floor-1), 0, 0.35) }
+
+func (g *Game) spawnerChance() float64 { return Clamp(0.12*float64(g.Floor-1), 0, 0.5) }
+
+func (g *Game) waveChance() float64 { return Clamp(0.08+0.08*float64(g.Floor-1), 0, 0.6) }
+
+func (g *Game) rollEnemy(slot Vec2, depth int) Enemy {
+	kindRoll := g.rng.Intn(100)
+	kind := EnemyChaser
+	hp := int(float64(2+depth/2+g.Floor/2) * g.modeHPScale())
+	switch {
+	case kindRoll < 28:
+		kind = EnemyChaser
+	case kindRoll < 52:
+		kind = EnemyWander
+	case kindRoll < 84:
+		kind = EnemyShooter
+	default:
+		kind = EnemyDasher
+	}
+	x := slot.X + (g.rng.Float64()*24 - 12)
+	y := slot.Y + (g.rng.Float64()*24 - 12)
+	e := Enemy{Pos: Vec2{X: x, Y: y}, HP: hp, MaxHP: hp, Kind: kind, Alive: true}
+	if kind == EnemyShooter {
+		e.ShootCooldown = g.Tune.EnemyShotDelay - minInt(35, depth*4)
+	}
+	if kind == EnemyDasher {
+		e.WanderTimer = 40 + g.rng.Intn(30)
+	}
+	if g.rng.Float64() < g.championChance() {
+		g.makeChampion(&e)
+	}
+	return e
+}
+
+func (g *Game) newSpawner(slot Vec2, depth int) Enemy {
+	hp := int(float64(6+depth+g.Floor) * g.modeHPScale())
+	e := Enemy{Pos: slot, HP: hp, MaxHP: hp, Kind: EnemySpawner, Alive: true, SpawnCD: spawnerDelayTicks / 2}
+	if g.rng.Float64() < g.championChance() {
+		g.makeChampion(&e)
+	}
+	return e
+}
+
+func (g *Game) newMinion(pos Vec2) Enemy {
+	a := g.rng.Float64() * 2 * math.Pi
+	p := Vec2{X: pos.X + math.Cos(a)*18, Y: pos.Y + math.Sin(a)*18}
+	hp := 1 + g.Floor/2
+	return Enemy{Pos: p, Prev: p, HP: hp, MaxHP: hp, Kind: EnemyChaser, Alive: true, Minion: true}
+}
+
+// makeChampion picks a modifier that makes sense for the enemy kind: only
+// enemies with a timer can be Rapid, and spawners never move.
+func (g *Game) makeChampion(e *Enemy) {
+	options := []ChampionType{ChampionSplit, ChampionSwift}
+	switch e.Kind {
+	case EnemyShooter, EnemyDasher:
+		options = append(options, ChampionRapid)
+	case EnemySpawner:
+		options = []ChampionType{ChampionRapid}
+	}
+	e.Champion = options[g.rng.Intn(len(options))]
+	e.HP *= championHPMult
+	e.MaxHP = e.HP
+}
+
+func enemySpeedMult(e *Enemy) float64 {
+	if e.Champion == ChampionSwift {
+		return championSpeedMult
+	}
+	return 1
+}
+
+// enemyTimer shortens shot, dash and spawn timers for Rapid champions.
+func enemyTimer(e *Enemy, ticks int) int {
+	if e.Champion == ChampionRapid {
+		return ticks / 2
+	}
+	return ticks
+}
+
+func (g *Game) rollWaves(tpl RoomTemplate, depth int) [][]Enemy {
+	if g.rng.Float64() >= g.waveChance() {
+		return nil
+	}
+	count := 1
+	if g.Floor >= 4 && g.rng.Intn(2) == 0 {
+		count++
+	}
+	waves := make([][]Enemy, 0, count)
+	for w := 0; w < count; w++ {
+		n := minInt(len(tpl.EnemySlots), 2+g.Floor/2)
+		wave := make([]Enemy, 0, n)
+		for i := 0; i < n; i++ {
+			wave = append(wave, g.rollModEnemy(g.rollEnemy(tpl.EnemySlots[g.rng.Intn(len(tpl.EnemySlots))], depth)))
+		}
+		waves = append(waves, wave)
+	}
+	return waves
+}
+
+func (g *Game) updateSpawner(e *Enemy) (Enemy, bool) {
+	e.Vel = Vec2{}
+	e.SpawnCD--
+	if e.SpawnCD > 0 {
+		return Enemy{}, false
+	}
+	e.SpawnCD = enemyTimer(e, int(float64(spawnerDelayTicks)/g.enemyDifficultyScale()))
+	if g.aliveMinionCount() >= spawnerMinionCap {
+		return Enemy{}, false
+	}
+	g.FX.burst(e.Pos, 6, 1.8, 16, 2, EnemyColor(*e))
+	g.emitEvent("spawner_spawn")
+	return g.newMinion(e.Pos), true
+}
+
+func (g *Game) aliveMinionCount() int {
+	n := 0
+	for _, e := range g.Enemies {
+		if e.Alive && e.Minion {
+			n++
+		}
+	}
+	return n
+}
+
+// onChampionKilled splits Split champions into minions and always leaves a
+// pickup behind.
+func (g *Game) onChampionKilled(e Enemy) {
+	if e.Champion == ChampionSplit {
+		for i := 0; i < splitMinions; i++ {
+			g.Enemies = append(g.Enemies, g.newMinion(e.Pos))
+		}
+	}
+	g.dropLoot(e.Pos, true)
+	g.emitEvent("champion_kill")
+}
+
+// updateWaves keeps the room shut while waves remain and brings in the next
+// one after a short pause. It reports whether a wave is still pending.
+func (g *Game) updateWaves() bool {
+	room := g.CurrentRoom()
+	if len(room.Waves) == 0 {
+		return false
+	}
+	g.waveDelay++
+	if g.waveDelay >= waveDelayTicks {
+		g.waveDelay = 0
+		g.spawnNextWave()
+	}
+	return true
+}
+
+func (g *Game) spawnNextWave() {
+	room := g.CurrentRoom()
+	wave := room.Waves[0]
+	room.Waves = room.Waves[1:]
+	for _, e := range wave {
+		e = g.readyEnemy(e)
+		g.FX.burst(e.Pos, 8, 2.2, 18, 2, EnemyColor(e))
+		g.Enemies = append(g.Enemies, e)
+	}
+	g.StatusText = g.Tr("wave.incoming", room.WaveTotal-len(room.Waves), room.WaveTotal)
+	g.StatusTextTick = 90
+	g.emitEvent("wave_start")
+}
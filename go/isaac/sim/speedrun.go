@@ -0,0 +1,235 @@
+package sim
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Split is a named point in a run and the run time, in ticks, it was reached.
+type Split struct {
+	Name  string `json:"name"`
+	Ticks int    `json:"ticks"`
+}
+
+// SplitFile is the personal best for one mode, saved next to the meta save.
+// Gold holds the fastest time ever for each segment, by name, even from runs
+// that were not a PB.
+type SplitFile struct {
+	Mode     string         `json:"mode"`
+	Attempts int            `json:"attempts"`
+	PB       []Split        `json:"pb"`
+	Gold     map[string]int `json:"gold"`
+}
+
+// SplitResult is a split of the current run compared against the PB.
+type SplitResult struct {
+	Split
+	Delta   int  `json:"delta"`  // ticks ahead (<0) or behind (>0) the PB at this split
+	HasPB   bool `json:"has_pb"` // the PB reached this split
+	Gold    bool `json:"gold"`   // best segment ever
+	Segment int  `json:"segment"`
+}
+
+// SpeedrunSnapshot is what the local socket serves. The game thread copies it
+// in once per frame, so the server never touches live game state.
+type SpeedrunSnapshot struct {
+	Mode   string        `json:"mode"`
+	Ticks  int           `json:"ticks"`
+	Time   string        `json:"time"`
+	Paused bool          `json:"paused"`
+	Dead   bool          `json:"dead"`
+	Splits []SplitResult `json:"splits"`
+}
+
+type speedrunServer struct {
+	mu   sync.Mutex
+	snap SpeedrunSnapshot
+}
+
+// Split names are what split files store and match on, so they are the same
+// in every language; SplitLabel is what the player sees.
+const (
+	splitFloor = "Floor %d"
+	splitBoss  = "Boss %d"
+)
+
+// SplitLabel translates a split name for display.
+func (g *Game) SplitLabel(name string) string {
+	var n int
+	if _, err := fmt.Sscanf(name, splitFloor, &n); err == nil {
+		return g.Tr("speedrun.split_floor", n)
+	}
+	if _, err := fmt.Sscanf(name, splitBoss, &n); err == nil {
+		return g.Tr("speedrun.split_boss", n)
+	}
+	return name
+}
+
+func (g *Game) splitsPath() string {
+	return filepath.Join(filepath.Dir(g.metaPath()), "splits_"+g.Mode.String()+".json")
+}
+
+func (g *Game) loadSplitFile() {
+	g.SplitFile = SplitFile{Mode: g.Mode.String(), Gold: map[string]int{}}
+	data, err := os.ReadFile(g.splitsPath())
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, &g.SplitFile)
+	if g.SplitFile.Gold == nil {
+		g.SplitFile.Gold = map[string]int{}
+	}
+}
+
+// FormatSplitTime shows ticks as m:ss.mmm; a tick is exactly 1/60 s, so the
+// time is frame accurate whatever the display rate.
+func FormatSplitTime(ticks int) string {
+	ms := ticks * 1000 / SimTPS
+	return fmt.Sprintf("%d:%02d.%03d", ms/60000, ms/1000%60, ms%1000)
+}
+
+func FormatDelta(ticks int) string {
+	sign := "+"
+	if ticks < 0 {
+		sign, ticks = "-", -ticks
+	}
+	ms := ticks * 1000 / SimTPS
+	if ms >= 60000 {
+		return fmt.Sprintf("%s%d:%02d.%d", sign, ms/60000, ms/1000%60, ms/100%10)
+	}
+	return fmt.Sprintf("%s%d.%d", sign, ms/1000, ms/100%10)
+}
+
+// split records a split at the current run time. The timer is runTicks, which
+// only advances in step, so pausing, the console and menus never count.
+func (g *Game) split(name string) {
+	r := SplitResult{Split: Split{Name: name, Ticks: g.RunTicks}, Segment: g.RunTicks}
+	if n := len(g.Splits); n > 0 {
+		r.Segment -= g.Splits[n-1].Ticks
+	}
+	if i := len(g.Splits); i < len(g.SplitFile.PB) && g.SplitFile.PB[i].Name == name {
+		r.HasPB = true
+		r.Delta = r.Ticks - g.SplitFile.PB[i].Ticks
+	}
+	if best, ok := g.SplitFile.Gold[name]; !ok || r.Segment < best {
+		r.Gold = ok
+		if !g.unranked() {
+			g.SplitFile.Gold[name] = r.Segment
+		}
+	}
+	g.Splits = append(g.Splits, r)
+}
+
+// finishSplits closes the attempt: a run that got further than the PB, or
+// as far but faster, becomes the new PB. Unranked runs are not kept.
+func (g *Game) finishSplits() {
+	if len(g.Splits) == 0 {
+		return
+	}
+	defer func() { g.Splits = g.Splits[:0] }()
+	if g.unranked() {
+		return
+	}
+	f := &g.SplitFile
+	f.Attempts++
+	last := g.Splits[len(g.Splits)-1].Ticks
+	if len(g.Splits) > len(f.PB) || (len(g.Splits) == len(f.PB) && last < f.PB[len(f.PB)-1].Ticks) {
+		f.PB = f.PB[:0]
+		for _, s := range g.Splits {
+			f.PB = append(f.PB, s.Split)
+		}
+		g.StatusText = g.Tr("speedrun.pb", FormatSplitTime(last))
+		g.StatusTextTick = 180
+	}
+	if g.Playback {
+		return
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(g.splitsPath(), data, 0644)
+}
+
+func (g *Game) bossSplitName() string {
+	n := 1
+	for _, s := range g.Splits {
+		if strings.HasPrefix(s.Name, "Boss ") {
+			n++
+		}
+	}
+	return fmt.Sprintf(splitBoss, n)
+}
+
+// PublishSpeedrun hands the socket a copy of the timer state. Frontends call
+// it once per frame.
+func (g *Game) PublishSpeedrun() {
+	if g.speedrunSrv == nil {
+		return
+	}
+	snap := SpeedrunSnapshot{
+		Mode:   g.Mode.String(),
+		Ticks:  g.RunTicks,
+		Time:   FormatSplitTime(g.RunTicks),
+		Paused: g.Paused || g.Scene != ScenePlaying,
+		Dead:   g.PlayerHP <= 0,
+		Splits: append([]SplitResult(nil), g.Splits...),
+	}
+	g.speedrunSrv.mu.Lock()
+	g.speedrunSrv.snap = snap
+	g.speedrunSrv.mu.Unlock()
+}
+
+// startSpeedrunServer listens on localhost for timer tools. The protocol is
+// one command per line: "time" answers with the run time, "state" with the
+// snapshot as a JSON line.
+func (g *Game) startSpeedrunServer() {
+	if g.Settings.SpeedrunPort <= 0 {
+		return
+	}
+	ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", g.Settings.SpeedrunPort))
+	if err != nil {
+		log.Printf("speedrun socket: %v", err)
+		return
+	}
+	g.speedrunSrv = &speedrunServer{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go g.speedrunSrv.serve(conn)
+		}
+	}()
+}
+
+func (s *speedrunServer) serve(conn net.Conn) {
+	defer conn.Close()
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		s.mu.Lock()
+		snap := s.snap
+		s.mu.Unlock()
+		var reply string
+		switch strings.TrimSpace(sc.Text()) {
+		case "time":
+			reply = snap.Time
+		case "state":
+			data, _ := json.Marshal(snap)
+			reply = string(data)
+		default:
+			reply = "unknown command"
+		}
+		if _, err := fmt.Fprintln(conn, reply); err != nil {
+			return
+		}
+	}
+}
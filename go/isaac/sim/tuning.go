@@ -0,0 +1,154 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Tuning holds the balance values designers play with. The defaults are the
+// shipped game; tuning.json overrides any subset of them, and debug builds
+// re-read the file while a run is going.
+type Tuning struct {
+	DashTicks     int     `json:"dash_ticks"`
+	DashCooldown  int     `json:"dash_cooldown"`
+	DashSpeedMult float64 `json:"dash_speed_mult"`
+	HurtCooldown  int     `json:"hurt_cooldown"`
+
+	BulletSpeed  float64 `json:"bullet_speed"`
+	BulletRadius float64 `json:"bullet_radius"`
+
+	ChaserSpeed     float64 `json:"chaser_speed"`
+	WanderSpeed     float64 `json:"wander_speed"`
+	ShooterSpeed    float64 `json:"shooter_speed"`
+	EnemyRadius     float64 `json:"enemy_radius"`
+	EnemyShotRadius float64 `json:"enemy_shot_radius"`
+	EnemyShotSpeed  float64 `json:"enemy_shot_speed"`
+	EnemyShotDelay  int     `json:"enemy_shot_delay"`
+	ContactDamage   int     `json:"contact_damage"`
+
+	BossHP          int     `json:"boss_hp"`
+	BossRadius      float64 `json:"boss_radius"`
+	BossSpeed       float64 `json:"boss_speed"`
+	BossSpeedP2     float64 `json:"boss_speed_p2"`
+	BossSpeedP3     float64 `json:"boss_speed_p3"`
+	BossShotSpeed   float64 `json:"boss_shot_speed"`
+	BossShotRadius  float64 `json:"boss_shot_radius"`
+	BossShotDamage  int     `json:"boss_shot_damage"`
+	BossShotDelay   int     `json:"boss_shot_delay"`
+	BossShotDelayP2 int     `json:"boss_shot_delay_p2"`
+	BossSpreadRad   float64 `json:"boss_spread_rad"`
+	BossSpreadCount int     `json:"boss_spread_count"`
+	BossRingCountP3 int     `json:"boss_ring_count_p3"`
+	BossRingDelayP3 int     `json:"boss_ring_delay_p3"`
+	BossWindupTicks int     `json:"boss_windup_ticks"`
+	BossPhase3HP    int     `json:"boss_phase3_hp"`
+
+	BombFuse     int     `json:"bomb_fuse"`
+	BombRadius   float64 `json:"bomb_radius"`
+	BombDamage   int     `json:"bomb_damage"`
+	BombCooldown int     `json:"bomb_cooldown"`
+
+	PickupRadius float64            `json:"pickup_radius"`
+	NoDropWeight float64            `json:"no_drop_weight"`
+	EnemyDrops   map[string]float64 `json:"enemy_drops"` // chance out of 1 per enemy, by pickup
+	ChestDrops   map[string]float64 `json:"chest_drops"` // relative weights
+}
+
+func defaultTuning() Tuning {
+	return Tuning{
+		DashTicks:       8,
+		DashCooldown:    48,
+		DashSpeedMult:   2.8,
+		HurtCooldown:    40,
+		BulletSpeed:     7.2,
+		BulletRadius:    4,
+		ChaserSpeed:     1.4,
+		WanderSpeed:     1.1,
+		ShooterSpeed:    0.9,
+		EnemyRadius:     13,
+		EnemyShotRadius: 4,
+		EnemyShotSpeed:  3.6,
+		EnemyShotDelay:  90,
+		ContactDamage:   1,
+		BossHP:          26,
+		BossRadius:      24,
+		BossSpeed:       1.0,
+		BossSpeedP2:     1.35,
+		BossSpeedP3:     1.55,
+		BossShotSpeed:   4.3,
+		BossShotRadius:  5,
+		BossShotDamage:  1,
+		BossShotDelay:   48,
+		BossShotDelayP2: 26,
+		BossSpreadRad:   0.35,
+		BossSpreadCount: 3,
+		BossRingCountP3: 8,
+		BossRingDelayP3: 120,
+		BossWindupTicks: 16,
+		BossPhase3HP:    7,
+		BombFuse:        90,
+		BombRadius:      74,
+		BombDamage:      7,
+		BombCooldown:    10,
+		PickupRadius:    9,
+		NoDropWeight:    0.174,
+		EnemyDrops: map[string]float64{
+			"heart": 0.14, "soul_heart": 0.02, "bomb": 0.11, "golden_bomb": 0.003, "coin": 0.38, "nickel": 0.04,
+			"dime": 0.01, "key": 0.10, "golden_key": 0.003, "battery": 0.01, "trinket": 0.004,
+		},
+		ChestDrops: map[string]float64{
+			"coins": 26, "nickel": 4, "bomb": 22, "golden_bomb": 2, "heart": 14, "soul_heart": 5,
+			"heart_container": 1, "key": 13, "golden_key": 2, "battery": 3, "trinket": 4, "score": 8,
+		},
+	}
+}
+
+func TuningPath() string { return filepath.Join(".", "tuning.json") }
+
+// LoadTuning returns the defaults with the file laid over them, and the
+// file's modification time so the watcher can tell when it changes. A file
+// that does not parse is an error; callers keep the values they had.
+func LoadTuning() (Tuning, time.Time, error) {
+	t := defaultTuning()
+	info, err := os.Stat(TuningPath())
+	if err != nil {
+		return t, time.Time{}, nil
+	}
+	data, err := os.ReadFile(TuningPath())
+	if err != nil {
+		return t, info.ModTime(), err
+	}
+	if err := json.Unmarshal(data, &t); err != nil {
+		return t, info.ModTime(), fmt.Errorf("%s: %w", TuningPath(), err)
+	}
+	t.BossHP = maxInt(1, t.BossHP)
+	return t, info.ModTime(), nil
+}
+
+// Lines lists the current values by their file names for the overlay. Maps
+// get a line per key, as name.key.
+func (t Tuning) Lines() []string {
+	v := reflect.ValueOf(t)
+	lines := make([]string, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Tag.Get("json")
+		if m, ok := v.Field(i).Interface().(map[string]float64); ok {
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				lines = append(lines, fmt.Sprintf("%s.%s = %v", name, k, m[k]))
+			}
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s = %v", name, v.Field(i).Interface()))
+	}
+	return lines
+}
@@ -0,0 +1,315 @@
+package sim
+
+import "math"
+
+// WeaponFlag is a set of attack transforms granted by items. The weapon is
+// resolved from the set, never from pickup order:
+//   - laser beats charge beats plain tears for how the attack fires;
+//   - with a laser, the charge item lets the beam overcharge for up to double
+//     damage and explosive makes the beam's end detonate;
+//   - homing, boomerang and explosive all stack on tears. A returning
+//     boomerang ignores homing.
+type WeaponFlag int
+
+const (
+	WeaponLaser WeaponFlag = 1 << iota
+	WeaponCharge
+	WeaponHoming
+	WeaponBoomerang
+	WeaponExplosive
+)
+
+// tearMods are the flags that ride on each tear rather than changing how the
+// attack is fired.
+const tearMods = WeaponHoming | WeaponBoomerang | WeaponExplosive
+
+const (
+	laserChargeMult    = 4 // laser charge time in multiples of the fire cooldown
+	chargeShotMult     = 5
+	chargeShotMaxScale = 3.0
+	laserDamageMult    = 3.0
+	beamWidth          = 14
+	BeamTicks          = 12
+	homingRange        = 260
+	homingTurn         = 0.12
+	boomerangTicks     = 24
+	explosiveCDMult    = 2
+)
+
+// Beam is a fired laser, kept only to be drawn while it fades.
+type Beam struct {
+	From  Vec2
+	To    Vec2
+	Timer int
+	Width float64
+}
+
+var weaponItemKeys = map[ItemType]string{
+	ItemLaser:      "item.laser",
+	ItemChargeShot: "item.charge_shot",
+	ItemHoming:     "item.homing",
+	ItemBoomerang:  "item.boomerang",
+	ItemExplosive:  "item.explosive",
+}
+
+var weaponItems = map[ItemType]WeaponFlag{
+	ItemLaser:      WeaponLaser,
+	ItemChargeShot: WeaponCharge,
+	ItemHoming:     WeaponHoming,
+	ItemBoomerang:  WeaponBoomerang,
+	ItemExplosive:  WeaponExplosive,
+}
+
+func (g *Game) chargeMode() bool { return g.Weapon&(WeaponLaser|WeaponCharge) != 0 }
+
+func (g *Game) shotCooldown() int {
+	if g.Weapon&WeaponExplosive != 0 {
+		return g.trinketCooldown(g.ShotCooldownBase * explosiveCDMult)
+	}
+	return g.trinketCooldown(g.ShotCooldownBase)
+}
+
+func (g *Game) LaserChargeTicks() int { return g.ShotCooldownBase * laserChargeMult }
+
+func (g *Game) ChargeMax() int {
+	switch {
+	case g.Weapon&WeaponLaser != 0 && g.Weapon&WeaponCharge != 0:
+		return g.LaserChargeTicks() * 2
+	case g.Weapon&WeaponLaser != 0:
+		return g.LaserChargeTicks()
+	}
+	return g.ShotCooldownBase * chargeShotMult
+}
+
+// fireDir is where the player fires this tick: the aim, or the last
+// direction fired while fire is held without aiming, bent by aim assist.
+func (g *Game) fireDir() Vec2 {
+	dir := g.input.Aim
+	if dir == (Vec2{}) && g.input.FireHeld {
+		dir = g.lastAimDir
+	}
+	if g.input.AimAssist && dir != (Vec2{}) {
+		dir = g.assistAim(dir)
+	}
+	return dir
+}
+
+func (g *Game) tryShoot() {
+	dir := g.fireDir()
+	if g.chargeMode() {
+		g.updateCharge(dir)
+		return
+	}
+	if g.fireCooldown > 0 || dir == (Vec2{}) {
+		return
+	}
+	g.lastAimDir = dir
+	g.fireCooldown = g.shotCooldown()
+	g.fireTears(dir, 1)
+}
+
+// updateCharge builds up charge while the player aims and fires on release.
+func (g *Game) updateCharge(dir Vec2) {
+	if dir != (Vec2{}) {
+		g.lastAimDir = dir
+		if g.fireCooldown == 0 {
+			g.ChargeTicks = minInt(g.ChargeTicks+1, g.ChargeMax())
+		}
+		return
+	}
+	charge := g.ChargeTicks
+	g.ChargeTicks = 0
+	if charge == 0 {
+		return
+	}
+	if g.Weapon&WeaponLaser != 0 {
+		full := g.LaserChargeTicks()
+		if charge < full {
+			return
+		}
+		g.fireBeam(g.lastAimDir, 1+float64(charge-full)/float64(full))
+		g.fireCooldown = g.shotCooldown()
+		return
+	}
+	max := g.ChargeMax()
+	if charge < max/4 {
+		return
+	}
+	g.fireTears(g.lastAimDir, 1+(chargeShotMaxScale-1)*float64(charge)/float64(max))
+	g.fireCooldown = g.shotCooldown()
+}
+
+func (g *Game) newBullet(dir Vec2, pierce int, scale float64) Bullet {
+	return Bullet{
+		Pos:    g.PlayerPos,
+		Prev:   g.PlayerPos,
+		Vel:    g.tearVelocity(dir),
+		Active: true,
+		Pierce: pierce,
+		Flags:  g.Weapon & tearMods,
+		Scale:  scale,
+	}
+}
+
+// tearVelocity is the launch velocity of a tear. In inherit mode the player's
+// movement this tick is added on top, so tears lead or lag while running.
+func (g *Game) tearVelocity(dir Vec2) Vec2 {
+	v := Vec2{X: dir.X * g.Tune.BulletSpeed, Y: dir.Y * g.Tune.BulletSpeed}
+	if g.Settings.TearVelocity == TearsInherit {
+		v.X += g.PlayerPos.X - g.PlayerPrevPos.X
+		v.Y += g.PlayerPos.Y - g.PlayerPrevPos.Y
+	}
+	return v
+}
+
+func (g *Game) fireTears(dir Vec2, scale float64) {
+	g.Bullets = append(g.Bullets, g.newBullet(dir, g.pierceCount, scale))
+	if !g.multiShot {
+		return
+	}
+	side := Vec2{X: -dir.Y, Y: dir.X}
+	spread := 0.22
+	for _, s := range []float64{spread, -spread} {
+		v := Vec2{X: dir.X + side.X*s, Y: dir.Y + side.Y*s}
+		l := math.Hypot(v.X, v.Y)
+		if l > 0 {
+			g.Bullets = append(g.Bullets, g.newBullet(Vec2{X: v.X / l, Y: v.Y / l}, g.sideShotPierce(), scale))
+		}
+	}
+}
+
+func (g *Game) sideShotPierce() int {
+	if g.Character().Passive == PassiveTwinBond {
+		return g.pierceCount + 1
+	}
+	return maxInt(0, g.pierceCount-1)
+}
+
+// fireBeam hits every enemy along the line from the player to the wall.
+func (g *Game) fireBeam(dir Vec2, scale float64) {
+	to := wallHit(g.PlayerPos, dir)
+	g.Beams = append(g.Beams, Beam{From: g.PlayerPos, To: to, Timer: BeamTicks, Width: beamWidth * math.Min(scale, 1.6)})
+	for i := range g.Enemies {
+		e := &g.Enemies[i]
+		if !e.Alive || segmentDistance(e.Pos, g.PlayerPos, to) > beamWidth/2+g.enemyRadiusOf(*e) {
+			continue
+		}
+		g.spawnHitSparks(e.Pos)
+		g.hitEnemy(e, int(math.Ceil(float64(g.rollShotDamage())*laserDamageMult*scale)))
+	}
+	if g.Weapon&WeaponExplosive != 0 {
+		g.explodeBomb(to)
+	}
+	g.ShakeTick = 4
+	g.ShakeMag = 2
+	g.emitEvent("laser_fire")
+}
+
+func (g *Game) enemyRadiusOf(e Enemy) float64 {
+	if e.Kind == EnemyBoss {
+		return g.Tune.BossRadius
+	}
+	return g.Tune.EnemyRadius
+}
+
+func (g *Game) hitEnemy(e *Enemy, dmg int) {
+	e.HP -= dmg
+	g.runDamageDealt += dmg
+	if e.HP <= 0 {
+		e.Alive = false
+		g.onEnemyKilled(*e)
+	}
+	if len(g.mods) > 0 {
+		ev := g.enemyEvent(g.enemyIndex(e), *e)
+		ev["target"], ev["damage"] = "enemy", dmg
+		g.modHook("on_hit", ev)
+	}
+}
+
+// steerBullet applies the tear modifiers before the bullet moves.
+func (g *Game) steerBullet(b *Bullet) {
+	b.Age++
+	speed := math.Hypot(b.Vel.X, b.Vel.Y)
+	if b.Flags&WeaponBoomerang != 0 && !b.Returning && b.Age >= boomerangTicks {
+		b.Returning = true
+	}
+	if b.Returning {
+		d := Vec2{X: g.PlayerPos.X - b.Pos.X, Y: g.PlayerPos.Y - b.Pos.Y}
+		if l := math.Hypot(d.X, d.Y); l > 0 {
+			b.Vel = Vec2{X: d.X / l * speed, Y: d.Y / l * speed}
+		}
+		return
+	}
+	if b.Flags&WeaponHoming == 0 {
+		return
+	}
+	var target *Enemy
+	best := float64(homingRange)
+	for i := range g.Enemies {
+		if e := &g.Enemies[i]; e.Alive && Distance(b.Pos, e.Pos) < best {
+			target, best = e, Distance(b.Pos, e.Pos)
+		}
+	}
+	if target == nil {
+		return
+	}
+	cur := math.Atan2(b.Vel.Y, b.Vel.X)
+	want := math.Atan2(target.Pos.Y-b.Pos.Y, target.Pos.X-b.Pos.X)
+	diff := math.Remainder(want-cur, 2*math.Pi)
+	a := cur + Clamp(diff, -homingTurn, homingTurn)
+	b.Vel = Vec2{X: math.Cos(a) * speed, Y: math.Sin(a) * speed}
+}
+
+// bulletWall handles a tear leaving the room: boomerangs turn back, explosive
+// tears go off against the wall.
+func (g *Game) bulletWall(b *Bullet) {
+	if b.Flags&WeaponBoomerang != 0 && !b.Returning {
+		b.Returning = true
+		b.Pos = b.Prev
+		return
+	}
+	if b.Flags&WeaponExplosive != 0 {
+		g.explodeBomb(b.Prev)
+	}
+	b.Active = false
+}
+
+func (g *Game) updateBeams() {
+	alive := g.Beams[:0]
+	for _, bm := range g.Beams {
+		bm.Timer--
+		if bm.Timer > 0 {
+			alive = append(alive, bm)
+		}
+	}
+	g.Beams = alive
+}
+
+// wallHit is where a ray from p along dir meets the room walls.
+func wallHit(p, dir Vec2) Vec2 {
+	t := math.Inf(1)
+	if dir.X > 0 {
+		t = math.Min(t, (ScreenW-RoomMargin-p.X)/dir.X)
+	} else if dir.X < 0 {
+		t = math.Min(t, (RoomMargin-p.X)/dir.X)
+	}
+	if dir.Y > 0 {
+		t = math.Min(t, (ScreenH-RoomMargin-p.Y)/dir.Y)
+	} else if dir.Y < 0 {
+		t = math.Min(t, (RoomMargin-p.Y)/dir.Y)
+	}
+	if math.IsInf(t, 1) {
+		return p
+	}
+	return Vec2{X: p.X + dir.X*t, Y: p.Y + dir.Y*t}
+}
+
+func segmentDistance(p, a, b Vec2) float64 {
+	ab := Vec2{X: b.X - a.X, Y: b.Y - a.Y}
+	l2 := ab.X*ab.X + ab.Y*ab.Y
+	if l2 == 0 {
+		return Distance(p, a)
+	}
+	t := Clamp(((p.X-a.X)*ab.X+(p.Y-a.Y)*ab.Y)/l2, 0, 1)
+	return Distance(p, Vec2{X: a.X + ab.X*t, Y: a.Y + ab.Y*t})
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"isaac/sim"
+)
+
+func (g *Game) drawEnemy(dst *ebiten.Image, e sim.Enemy) {
+	r := float32(g.Tune.EnemyRadius)
+	col := sim.EnemyColor(e)
+	p := g.lerpPos(e.Prev, e.Pos)
+	x, y := float32(p.X), float32(p.Y)
+	switch e.Kind {
+	case sim.EnemyBoss:
+		r = float32(g.Tune.BossRadius)
+		if e.ShootWindup > 0 {
+			ringR := float32(g.Tune.BossRadius + 8 + float64(g.Tune.BossWindupTicks-e.ShootWindup))
+			vector.StrokeCircle(dst, x, y, ringR, 2, color.RGBA{R: 245, G: 120, B: 90, A: 255}, false)
+		}
+	case sim.EnemySpawner:
+		vector.DrawFilledRect(dst, x-r, y-r, 2*r, 2*r, col, false)
+		vector.DrawFilledCircle(dst, x, y, r*0.45, color.RGBA{R: 30, G: 20, B: 22, A: 255}, false)
+	}
+	if e.Kind != sim.EnemySpawner {
+		g.drawEnemyShape(dst, e, x, y, r, col)
+	}
+	if e.Champion != sim.ChampionNone {
+		vector.StrokeCircle(dst, x, y, r+4, 2, col, false)
+	}
+}
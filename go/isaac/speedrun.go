@@ -0,0 +1,64 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+
+	"isaac/sim"
+)
+
+const splitRows = 8
+
+var (
+	aheadColor  = color.RGBA{R: 110, G: 210, B: 120, A: 255}
+	behindColor = color.RGBA{R: 230, G: 95, B: 85, A: 255}
+)
+
+// drawSplits is the speedrun overlay on the left edge: the latest splits with
+// their delta against the PB, gold for a best segment, then the live timer.
+func (g *Game) drawSplits(v hudView) {
+	x, y := float64(hudMargin), 150.0
+	v.rect(x-4, y-4, 196, float64(splitRows+3)*14+10, hudPanelColor)
+	v.text(g.Tr("speedrun.title", g.ModeLabel(g.Mode), g.SplitFile.Attempts+1), x, y, hudDimColor, text.AlignStart)
+	rows := g.Splits
+	if len(rows) > splitRows {
+		rows = rows[len(rows)-splitRows:]
+	}
+	for i, s := range rows {
+		ry := y + float64(i+1)*14
+		v.text(g.SplitLabel(s.Name), x, ry, hudTextColor, text.AlignStart)
+		if s.HasPB {
+			col := aheadColor
+			if s.Delta > 0 {
+				col = behindColor
+			}
+			if s.Gold {
+				col = goldColor
+			}
+			v.text(sim.FormatDelta(s.Delta), x+120, ry, col, text.AlignEnd)
+		}
+		v.text(sim.FormatSplitTime(s.Ticks), x+188, ry, hudTextColor, text.AlignEnd)
+	}
+	col := hudTextColor
+	if next := len(g.Splits); next < len(g.SplitFile.PB) {
+		pb := g.SplitFile.PB[next]
+		v.text(g.Tr("speedrun.next", g.SplitLabel(pb.Name), sim.FormatSplitTime(pb.Ticks)), x, y+float64(splitRows+1)*14, hudDimColor, text.AlignStart)
+		if g.RunTicks > pb.Ticks {
+			col = behindColor
+		}
+	}
+	if g.Paused {
+		col = hudDimColor
+	}
+	v.text(sim.FormatSplitTime(g.RunTicks), x+188, y+float64(splitRows+2)*14, col, text.AlignEnd)
+}
+
+func (g *Game) updateSpeedrun() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF4) {
+		g.Settings.Speedrun = !g.Settings.Speedrun
+	}
+	g.PublishSpeedrun()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"time"
+
+	"isaac/sim"
+)
+
+// holdTicks is how long movement and aim keep going after a key press.
+// Terminals report no key releases, only the auto-repeat of a held key, so a
+// direction lingers long enough to bridge the repeat delay.
+const holdTicks = 30
+
+// escWait is how long a lone Esc waits for the rest of an arrow key's escape
+// sequence before it counts as a quit. Over ssh the bytes of one key can
+// arrive in separate reads.
+const escWait = 50 * time.Millisecond
+
+// controls turns terminal key presses into tick input. Only one move and one
+// aim direction are held at a time, since a terminal repeats only the last
+// key pressed.
+type controls struct {
+	move, aim           sim.Vec2
+	moveTicks, aimTicks int
+	presses             sim.TickInput
+
+	// esc holds an escape sequence cut off at the end of a read, and escAt
+	// when it arrived.
+	esc   []byte
+	escAt time.Time
+}
+
+var moveKeys = map[byte]sim.Vec2{
+	'w': {Y: -1}, 'a': {X: -1}, 's': {Y: 1}, 'd': {X: 1},
+}
+
+var aimKeys = map[byte]sim.Vec2{
+	'i': {Y: -1}, 'j': {X: -1}, 'k': {Y: 1}, 'l': {X: 1},
+	'A': {Y: -1}, 'D': {X: -1}, 'B': {Y: 1}, 'C': {X: 1}, // arrow escape finals
+}
+
+// feed applies one read from the terminal, received at now. It reports false
+// when the player quits with Ctrl-C or Esc.
+func (c *controls) feed(g *sim.Game, buf []byte, now time.Time) bool {
+	if len(c.esc) > 0 {
+		buf, now = append(c.esc, buf...), c.escAt
+		c.esc = nil
+	}
+	for i := 0; i < len(buf); i++ {
+		k := buf[i]
+		if k == 0x1b {
+			if i+1 == len(buf) || (i+2 == len(buf) && (buf[i+1] == '[' || buf[i+1] == 'O')) {
+				c.esc, c.escAt = append([]byte(nil), buf[i:]...), now
+				return true
+			}
+			if buf[i+1] == '[' || buf[i+1] == 'O' {
+				if dir, ok := aimKeys[buf[i+2]]; ok {
+					c.aim, c.aimTicks = dir, holdTicks
+				}
+				i += 2
+				continue
+			}
+			return false
+		}
+		if dir, ok := moveKeys[k]; ok {
+			c.move, c.moveTicks = dir, holdTicks
+			continue
+		}
+		if dir, ok := moveKeys[k|0x20]; ok {
+			// Shift with a direction dashes that way.
+			c.move, c.moveTicks = dir, holdTicks
+			c.presses.Dash = true
+			continue
+		}
+		if dir, ok := aimKeys[k]; ok && k >= 'a' {
+			c.aim, c.aimTicks = dir, holdTicks
+			continue
+		}
+		switch k {
+		case 3:
+			return false
+		case ' ':
+			c.moveTicks, c.aimTicks = 0, 0
+		case 'e':
+			c.presses.Bomb = true
+		case 'q':
+			c.presses.Active = true
+		case 'g':
+			c.presses.Chest = true
+		case 'f':
+			c.presses.Buy = true
+		case 'h':
+			c.presses.Reroll = true
+		case 'x':
+			c.presses.Descend = true
+		case 't':
+			c.presses.Trinket = true
+		case 'p':
+			g.Paused = !g.Paused
+		case 'n':
+			g.StartNewRun()
+			*c = controls{}
+		case 'r':
+			if g.PlayerHP <= 0 {
+				g.ResetRun()
+				*c = controls{}
+			}
+		}
+	}
+	return true
+}
+
+// expire settles an escape sequence still incomplete at now. It reports
+// false when a lone Esc has waited escWait, which quits; a cut-off arrow key
+// is dropped.
+func (c *controls) expire(now time.Time) bool {
+	if len(c.esc) == 0 || now.Sub(c.escAt) < escWait {
+		return true
+	}
+	lone := len(c.esc) == 1
+	c.esc = nil
+	return !lone
+}
+
+// next is the input for one simulation tick. Presses are seen once.
+func (c *controls) next() sim.TickInput {
+	in := c.presses
+	c.presses = sim.TickInput{}
+	if c.moveTicks > 0 {
+		c.moveTicks--
+		in.Move = c.move
+	}
+	if c.aimTicks > 0 {
+		c.aimTicks--
+		in.Aim = c.aim
+	}
+	return in
+}
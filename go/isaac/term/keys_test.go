@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"isaac/sim"
+)
+
+// TestSplitArrowKey feeds an arrow key whose escape sequence arrives over
+// two reads, as it can over ssh; it must aim, not quit.
+func TestSplitArrowKey(t *testing.T) {
+	var c controls
+	g := &sim.Game{}
+	now := time.Now()
+	if !c.feed(g, []byte{0x1b}, now) {
+		t.Fatal("a lone Esc quit before the rest of the sequence could arrive")
+	}
+	if !c.feed(g, []byte("[A"), now.Add(10*time.Millisecond)) {
+		t.Fatal("the rest of the arrow key quit")
+	}
+	if in := c.next(); in.Aim != (sim.Vec2{Y: -1}) {
+		t.Errorf("aim is %v, want up", in.Aim)
+	}
+	if !c.expire(now.Add(time.Second)) {
+		t.Error("a completed sequence still quit later")
+	}
+}
+
+func TestLoneEscQuits(t *testing.T) {
+	var c controls
+	g := &sim.Game{}
+	now := time.Now()
+	c.feed(g, []byte{'w', 0x1b}, now)
+	if !c.expire(now.Add(escWait / 2)) {
+		t.Fatal("Esc quit before escWait")
+	}
+	if c.expire(now.Add(escWait)) {
+		t.Error("Esc did not quit after escWait")
+	}
+}
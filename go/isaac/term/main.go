@@ -0,0 +1,81 @@
+// Command term plays the game in a terminal. It drives the same simulation
+// as the windowed build and draws each frame as a character grid.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"isaac/sim"
+)
+
+// drawEvery is how many ticks pass between redraws; a full-speed terminal
+// redraw flickers more than it helps.
+const drawEvery = 2
+
+func main() {
+	seed := flag.Int64("seed", 0, "run seed; 0 picks one from the clock")
+	flag.Parse()
+
+	restore, err := makeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "term:", err)
+		os.Exit(1)
+	}
+	defer restore()
+	fmt.Print("\x1b[?25l\x1b[2J")
+	defer fmt.Print("\x1b[?25h\x1b[2J\x1b[H")
+
+	g := sim.NewGame()
+	if *seed != 0 {
+		g.StartRunWithSeed(*seed)
+	}
+	keys := make(chan []byte, 16)
+	go readKeys(keys)
+
+	var ctl controls
+	var speed float64
+	ticker := time.NewTicker(time.Second / sim.SimTPS)
+	defer ticker.Stop()
+	for frame := 0; ; frame++ {
+		<-ticker.C
+		for pending := true; pending; {
+			select {
+			case buf, ok := <-keys:
+				if !ok || !ctl.feed(g, buf, time.Now()) {
+					return
+				}
+			default:
+				pending = false
+			}
+		}
+		if !ctl.expire(time.Now()) {
+			return
+		}
+		if !g.Paused && g.PlayerHP > 0 {
+			speed += g.Settings.GameSpeed
+			for ; speed >= 1 && g.PlayerHP > 0; speed-- {
+				g.Step(ctl.next())
+			}
+		}
+		g.PublishSpeedrun()
+		if frame%drawEvery == 0 {
+			fmt.Print("\x1b[H" + strings.ReplaceAll(render(g), "\n", "\x1b[K\r\n") + "\x1b[J")
+		}
+	}
+}
+
+func readKeys(out chan<- []byte) {
+	buf := make([]byte, 64)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(out)
+			return
+		}
+		out <- append([]byte(nil), buf[:n]...)
+	}
+}
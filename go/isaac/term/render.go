@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"isaac/sim"
+)
+
+// The 960x540 room maps onto an 80x27 character grid; a cell is taller than
+// it is wide, as terminal glyphs are.
+const (
+	gridW = 80
+	gridH = 27
+	cellW = sim.ScreenW / gridW
+	cellH = sim.ScreenH / gridH
+)
+
+var enemyGlyphs = map[sim.EnemyType]byte{
+	sim.EnemyChaser:  'c',
+	sim.EnemyWander:  'w',
+	sim.EnemyShooter: 's',
+	sim.EnemyDasher:  'd',
+	sim.EnemyBoss:    'B',
+	sim.EnemySpawner: 'n',
+}
+
+var pickupGlyphs = map[sim.PickupType]byte{
+	sim.PickupHeart:          'h',
+	sim.PickupBomb:           'b',
+	sim.PickupCoin:           '$',
+	sim.PickupKey:            'k',
+	sim.PickupSoulHeart:      'u',
+	sim.PickupHeartContainer: 'H',
+	sim.PickupNickel:         '$',
+	sim.PickupDime:           '$',
+	sim.PickupGoldenKey:      'K',
+	sim.PickupGoldenBomb:     'G',
+	sim.PickupBattery:        '=',
+	sim.PickupTrinket:        ',',
+}
+
+var offerGlyphs = map[sim.OfferType]byte{
+	sim.OfferHeart:     'h',
+	sim.OfferBombPack:  'b',
+	sim.OfferKey:       'k',
+	sim.OfferSoulHeart: 'u',
+	sim.OfferItem:      'I',
+}
+
+var doorGlyphs = map[sim.DoorState]byte{
+	sim.DoorOpen:   ' ',
+	sim.DoorClosed: '-',
+	sim.DoorLocked: 'L',
+	sim.DoorBoss:   'X',
+}
+
+type grid [gridH][gridW]byte
+
+func cellOf(p sim.Vec2) (int, int) {
+	return int(p.X) / cellW, int(p.Y) / cellH
+}
+
+func (gr *grid) set(p sim.Vec2, c byte) {
+	x, y := cellOf(p)
+	gr.put(x, y, c)
+}
+
+func (gr *grid) put(x, y int, c byte) {
+	if x >= 0 && x < gridW && y >= 0 && y < gridH {
+		gr[y][x] = c
+	}
+}
+
+func (gr *grid) text(x, y int, s string) {
+	for i := 0; i < len(s); i++ {
+		gr.put(x+i, y, s[i])
+	}
+}
+
+// disc fills every cell whose centre lies within r of p.
+func (gr *grid) disc(p sim.Vec2, r float64, c byte) {
+	x0, y0 := cellOf(sim.Vec2{X: p.X - r, Y: p.Y - r})
+	x1, y1 := cellOf(sim.Vec2{X: p.X + r, Y: p.Y + r})
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			cx, cy := float64(x*cellW+cellW/2), float64(y*cellH+cellH/2)
+			if math.Hypot(cx-p.X, cy-p.Y) <= r {
+				gr.put(x, y, c)
+			}
+		}
+	}
+	gr.set(p, c)
+}
+
+// line steps along a beam one cell at a time.
+func (gr *grid) line(a, b sim.Vec2, c byte) {
+	n := int(math.Max(math.Abs(b.X-a.X)/cellW, math.Abs(b.Y-a.Y)/cellH)) + 1
+	for i := 0; i <= n; i++ {
+		t := float64(i) / float64(n)
+		gr.set(sim.Vec2{X: a.X + (b.X-a.X)*t, Y: a.Y + (b.Y-a.Y)*t}, c)
+	}
+}
+
+// render draws the current room and a few HUD lines as plain text, one row
+// per line. It reads the game state only, so the same call serves the live
+// terminal and the snapshot test.
+func render(g *sim.Game) string {
+	var gr grid
+	for y := range gr {
+		for x := range gr[y] {
+			gr[y][x] = ' '
+		}
+	}
+	x0, y0 := cellOf(sim.Vec2{X: sim.RoomMargin, Y: sim.RoomMargin})
+	x1, y1 := cellOf(sim.Vec2{X: sim.ScreenW - sim.RoomMargin, Y: sim.ScreenH - sim.RoomMargin})
+	for x := x0; x <= x1; x++ {
+		gr.put(x, y0, '#')
+		gr.put(x, y1, '#')
+	}
+	for y := y0; y <= y1; y++ {
+		gr.put(x0, y, '#')
+		gr.put(x1, y, '#')
+	}
+	drawDoors(&gr, g)
+
+	room := g.CurrentRoom()
+	for _, h := range g.Hazards {
+		gr.disc(h.Pos, h.R, '^')
+	}
+	if g.RoomClear && !room.Reward.Taken {
+		c := byte('I')
+		if g.Curse == sim.CurseBlind {
+			c = '?'
+		}
+		gr.set(room.Reward.Pos, c)
+	}
+	for _, c := range g.Chests {
+		if c.Opened {
+			gr.set(c.Pos, '_')
+		} else {
+			gr.set(c.Pos, '&')
+		}
+	}
+	if room.Keeper {
+		gr.set(sim.ShopkeeperPos, 'Y')
+	}
+	for _, o := range g.Offers {
+		drawOffer(&gr, g, o)
+	}
+	for _, p := range g.Pickups {
+		if p.Active {
+			gr.set(p.Pos, pickupGlyphs[p.Kind])
+		}
+	}
+	for _, b := range g.BombList {
+		if b.Active {
+			gr.set(b.Pos, '%')
+		}
+	}
+	for _, ex := range g.Explosions {
+		gr.disc(ex.Pos, ex.Radius, 'x')
+	}
+	gr.set(g.PlayerPos, '@')
+	for _, f := range g.Familiars {
+		gr.set(f.Pos, 'f')
+	}
+	for _, b := range g.Bullets {
+		if b.Active {
+			gr.set(b.Pos, '*')
+		}
+	}
+	for _, b := range g.Beams {
+		gr.line(b.From, b.To, '~')
+	}
+	for _, s := range g.EnemyShots {
+		if s.Active {
+			gr.set(s.Pos, 'o')
+		}
+	}
+	for _, e := range g.Enemies {
+		if e.Alive {
+			gr.set(e.Pos, enemyGlyph(e))
+		}
+	}
+	if g.FloorCleared() {
+		gr.set(sim.Vec2{X: sim.ScreenW / 2, Y: sim.ScreenH / 2}, 'O')
+	}
+	if g.Curse == sim.CurseDarkness {
+		for y := y0 + 1; y < y1; y++ {
+			for x := x0 + 1; x < x1; x++ {
+				cx, cy := float64(x*cellW+cellW/2), float64(y*cellH+cellH/2)
+				if math.Hypot(cx-g.PlayerPos.X, cy-g.PlayerPos.Y) > sim.DarknessRadius {
+					gr[y][x] = ' '
+				}
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, row := range gr {
+		b.WriteString(strings.TrimRight(string(row[:]), " "))
+		b.WriteByte('\n')
+	}
+	for _, l := range hudLines(g) {
+		b.WriteString(l)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func drawDoors(gr *grid, g *sim.Game) {
+	for _, d := range sim.LayoutDirs {
+		door := g.DoorToward(d[0], d[1])
+		if door == nil {
+			continue
+		}
+		state := g.EffectiveDoorState(door)
+		if state == sim.DoorSecret {
+			continue
+		}
+		c := sim.DoorCenter(d[0], d[1])
+		glyph := doorGlyphs[state]
+		if d[0] != 0 {
+			if glyph == '-' {
+				glyph = '|'
+			}
+			gr.line(sim.Vec2{X: c.X, Y: c.Y - sim.DoorHalf + cellH/2}, sim.Vec2{X: c.X, Y: c.Y + sim.DoorHalf - cellH/2}, glyph)
+		} else {
+			gr.line(sim.Vec2{X: c.X - sim.DoorHalf + cellW/2, Y: c.Y}, sim.Vec2{X: c.X + sim.DoorHalf - cellW/2, Y: c.Y}, glyph)
+		}
+	}
+}
+
+func drawOffer(gr *grid, g *sim.Game, o sim.ShopOffer) {
+	if o.Purchased {
+		gr.set(o.Pos, '_')
+		return
+	}
+	c := offerGlyphs[o.Kind]
+	if o.Kind == sim.OfferItem && g.Curse == sim.CurseBlind {
+		c = '?'
+	}
+	gr.set(o.Pos, c)
+	label := fmt.Sprintf("%dc", g.OfferPrice(o))
+	if o.Free {
+		label = g.Tr("shop.free")
+	}
+	x, y := cellOf(o.Pos)
+	gr.text(x-len(label)/2, y+1, label)
+}
+
+// enemyGlyph is the enemy's letter, upper case for champions. Mod enemies
+// all share one letter.
+func enemyGlyph(e sim.Enemy) byte {
+	c, ok := enemyGlyphs[e.Kind]
+	if e.Mod != "" || !ok {
+		c = 'm'
+	}
+	if e.Champion != sim.ChampionNone && c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	return c
+}
+
+func hudLines(g *sim.Game) []string {
+	lines := []string{
+		g.Tr("term.hud", g.PlayerHP, g.MaxHP, g.SoulHP, g.Coins, g.Bombs, g.Keys) + "  " + g.Tr("hud.score", g.Score),
+		fmt.Sprintf("%s  %s  %s  %s", g.Character().Name, g.ModeLabel(g.Mode), sim.FormatRunTime(g.RunTicks), g.Tr("hud.floor", g.Floor, g.CurrentRoomID+1, len(g.Rooms), g.AliveEnemyCount(), g.RunRank())),
+	}
+	var slot []string
+	if g.Trinket != sim.TrinketNone {
+		slot = append(slot, g.TrinketName(g.Trinket))
+	}
+	if g.Curse != sim.CurseNone {
+		slot = append(slot, g.CurseName(g.Curse))
+	}
+	if len(slot) > 0 {
+		lines = append(lines, strings.Join(slot, "  "))
+	}
+	switch {
+	case g.PlayerHP <= 0:
+		lines = append(lines, g.Tr("hud.died"))
+	case g.Paused:
+		lines = append(lines, g.Tr("hud.paused"), g.Tr("term.controls"))
+	}
+	if g.StatusTextTick > 0 {
+		lines = append(lines, g.StatusText)
+	}
+	if g.ItemTextTicks > 0 {
+		lines = append(lines, g.LastItemText)
+	}
+	if g.RoomClear {
+		lines = append(lines, g.Tr("hud.room_clear"))
+	}
+	if g.FloorCleared() {
+		lines = append(lines, g.Tr("hud.descend"))
+	}
+	return lines
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"isaac/sim"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden frames in testdata")
+
+// TestRenderSnapshot plays a few scripted ticks of a seeded run and compares
+// the text frame with testdata. Run with -update after an intended change.
+func TestRenderSnapshot(t *testing.T) {
+	golden, err := filepath.Abs(filepath.Join("testdata", "seed42.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Start from an empty directory so saved settings, meta and mods on the
+	// machine do not leak into the frame.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	t.Setenv("ISAAC_LANG", "en")
+
+	g := sim.NewGame()
+	g.StartRunWithSeed(42)
+	var ctl controls
+	script := []struct {
+		keys  string
+		ticks int
+	}{
+		{"d", 20},
+		{"s", 10},
+		{"\x1b[A", 12},
+		{"j", 6},
+	}
+	for _, s := range script {
+		ctl.feed(g, []byte(s.keys), time.Now())
+		for i := 0; i < s.ticks; i++ {
+			g.Step(ctl.next())
+		}
+	}
+	got := render(g)
+
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("frame differs from %s:\n%s", golden, got)
+	}
+}
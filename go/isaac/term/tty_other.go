@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+import "errors"
+
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("raw terminal input is not supported on this platform")
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+
+	"isaac/sim"
+)
+
+const (
+	tuningPollTicks   = 30
+	tuningOverlayRows = 32
+)
+
+// watchTuning polls tuning.json in debug builds and swaps the new values in
+// as soon as it is saved. Everything reads g.Tune each tick, so the change
+// shows up at once; boss HP applies to the next boss spawned.
+func (g *Game) watchTuning() {
+	if !debugBuild {
+		return
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF3) {
+		g.showTuning = !g.showTuning
+	}
+	g.tunePollTick++
+	if g.tunePollTick%tuningPollTicks != 0 {
+		return
+	}
+	info, err := os.Stat(sim.TuningPath())
+	if err != nil || info.ModTime().Equal(g.tuneModTime) {
+		return
+	}
+	tune, modTime, err := sim.LoadTuning()
+	g.tuneModTime = modTime
+	if err != nil {
+		g.StatusText = g.Tr("debug.tuning_error", err)
+		g.StatusTextTick = 300
+		return
+	}
+	g.Tune = tune
+	g.StatusText = g.Tr("debug.tuning_reloaded")
+	g.StatusTextTick = 90
+}
+
+// drawTuningOverlay is the F3 panel of debug builds.
+func (g *Game) drawTuningOverlay(v hudView) {
+	lines := g.Tune.Lines()
+	cols := (len(lines) + tuningOverlayRows - 1) / tuningOverlayRows
+	rows := min(len(lines), tuningOverlayRows)
+	v.rect(hudMargin, 60, 250*float64(cols), float64(rows)*12+28, hudPanelColor)
+	v.text(g.Tr("debug.tuning_title"), hudMargin+8, 66, hudTextColor, text.AlignStart)
+	for i, l := range lines {
+		x := hudMargin + 8 + 250*float64(i/tuningOverlayRows)
+		v.text(l, x, 84+float64(i%tuningOverlayRows)*12, hudDimColor, text.AlignStart)
+	}
+}
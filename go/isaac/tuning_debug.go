@@ -0,0 +1,7 @@
+//go:build debug
+
+package main
+
+// debugBuild enables tuning hot reload and the tuning overlay. Build with
+// -tags debug.
+const debugBuild = true
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"isaac/sim"
+)
+
+func (g *Game) drawBullet(dst *ebiten.Image, b sim.Bullet) {
+	p := g.lerpPos(b.Prev, b.Pos)
+	r := float32(g.Tune.BulletRadius * math.Sqrt(b.Scale))
+	col := color.RGBA{R: 180, G: 220, B: 255, A: 255}
+	switch {
+	case b.Flags&sim.WeaponExplosive != 0:
+		col = color.RGBA{R: 150, G: 200, B: 90, A: 255}
+	case b.Flags&sim.WeaponHoming != 0:
+		col = color.RGBA{R: 200, G: 150, B: 240, A: 255}
+	}
+	vector.DrawFilledCircle(dst, float32(p.X), float32(p.Y), r, col, false)
+	if b.Flags&sim.WeaponBoomerang != 0 {
+		vector.StrokeCircle(dst, float32(p.X), float32(p.Y), r+2, 1.5, color.RGBA{R: 240, G: 220, B: 160, A: 255}, false)
+	}
+}
+
+func (g *Game) drawBeams(dst *ebiten.Image) {
+	for _, bm := range g.Beams {
+		a := uint8(255 * bm.Timer / sim.BeamTicks)
+		vector.StrokeLine(dst, float32(bm.From.X), float32(bm.From.Y), float32(bm.To.X), float32(bm.To.Y), float32(bm.Width), color.RGBA{R: 200, G: 30, B: 40, A: a}, false)
+		vector.StrokeLine(dst, float32(bm.From.X), float32(bm.From.Y), float32(bm.To.X), float32(bm.To.Y), float32(bm.Width/3), color.RGBA{R: 255, G: 200, B: 200, A: a}, false)
+	}
+}
+
+// drawChargeRing shows how far the current charge has built around the player.
+func (g *Game) drawChargeRing(dst *ebiten.Image, pos sim.Vec2) {
+	if g.ChargeTicks == 0 {
+		return
+	}
+	frac := float32(g.ChargeTicks) / float32(g.ChargeMax())
+	col := color.RGBA{R: 235, G: 235, B: 235, A: 200}
+	if g.Weapon&sim.WeaponLaser != 0 && g.ChargeTicks >= g.LaserChargeTicks() {
+		col = color.RGBA{R: 230, G: 60, B: 60, A: 230}
+	}
+	var p vector.Path
+	start := float32(-math.Pi / 2)
+	p.Arc(float32(pos.X), float32(pos.Y), sim.PlayerRadius+6, start, start+2*math.Pi*frac, vector.Clockwise)
+	strokePath(dst, &p, 3, col)
+}